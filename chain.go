@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/CaiqueRibeiro/blocker/node"
+	"github.com/CaiqueRibeiro/blocker/proto"
+)
+
+// Prints a block by height or by hash, in the JSON encoding of the HTTP gateway
+func runGetBlock(args []string) error {
+	var (
+		fs       = newFlagSet("chain get-block")
+		addr     = fs.String("node", ":3000", "address of the node")
+		height   = fs.Int("height", -1, "height of the block")
+		hash     = fs.String("hash", "", "hash of the block")
+		tlsFlags = addTLSFlags(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*height < 0) == (*hash == "") {
+		return fmt.Errorf("inform the height or the hash of the block")
+	}
+	conn, err := dial(*addr, tlsFlags)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	query := proto.NewQueryClient(conn)
+
+	var block *proto.Block
+	if *hash != "" {
+		b, err := hex.DecodeString(*hash)
+		if err != nil {
+			return fmt.Errorf("invalid block hash %q", *hash)
+		}
+		block, err = query.GetBlockByHash(context.Background(), &proto.GetBlockByHashRequest{Hash: b})
+		if err != nil {
+			return err
+		}
+	} else {
+		block, err = query.GetBlockByHeight(context.Background(), &proto.GetBlockByHeightRequest{Height: int32(*height)})
+		if err != nil {
+			return err
+		}
+	}
+	b, err := node.MarshalJSON(block)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/node"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix   = "BLOCKER_"
+	nodeKeyFile = "node.key"  // identity key of the node, in the data dir
+	banListFile = "bans.json" // banned peers, in the data dir
+)
+
+/*
+Configuration of a node, loaded from a YAML, TOML or JSON file and from BLOCKER_* environment variables.
+
+The fields have the same names in the three formats. See config.example.yaml for the documented defaults
+*/
+type Config struct {
+	Listen         string        `json:"listen" yaml:"listen" toml:"listen"`
+	Bootstrap      []string      `json:"bootstrap" yaml:"bootstrap" toml:"bootstrap"`
+	DataDir        string        `json:"datadir" yaml:"datadir" toml:"datadir"`
+	ValidatorKey   string        `json:"validatorKey" yaml:"validatorKey" toml:"validatorKey"`
+	Genesis        string        `json:"genesis" yaml:"genesis" toml:"genesis"`
+	PassphraseFile string        `json:"passphraseFile" yaml:"passphraseFile" toml:"passphraseFile"`
+	BlockTime      util.Duration `json:"blockTime" yaml:"blockTime" toml:"blockTime"`
+	HTTPListen     string        `json:"httpListen" yaml:"httpListen" toml:"httpListen"`
+	AdminListen    string        `json:"adminListen" yaml:"adminListen" toml:"adminListen"`
+	AdminToken     string        `json:"adminToken" yaml:"adminToken" toml:"adminToken"`
+	AllowedPeers   []string      `json:"allowedPeers" yaml:"allowedPeers" toml:"allowedPeers"`
+	TLS            TLS           `json:"tls" yaml:"tls" toml:"tls"`
+	Mempool        Mempool       `json:"mempool" yaml:"mempool" toml:"mempool"`
+	Log            Log           `json:"log" yaml:"log" toml:"log"`
+}
+
+// TLS of the connections between nodes. Empty cert and key use insecure connections
+type TLS struct {
+	Cert              string `json:"cert" yaml:"cert" toml:"cert"`
+	Key               string `json:"key" yaml:"key" toml:"key"`
+	CA                string `json:"ca" yaml:"ca" toml:"ca"`
+	RequireClientCert bool   `json:"requireClientCert" yaml:"requireClientCert" toml:"requireClientCert"`
+	ServerName        string `json:"serverName" yaml:"serverName" toml:"serverName"`
+}
+
+func (t TLS) enabled() bool {
+	return t != TLS{}
+}
+
+type Mempool struct {
+	MaxTxs int `json:"maxTxs" yaml:"maxTxs" toml:"maxTxs"`
+}
+
+type Log struct {
+	Level  string `json:"level" yaml:"level" toml:"level"`
+	Format string `json:"format" yaml:"format" toml:"format"`
+}
+
+func Default() *Config {
+	return &Config{
+		Listen:       ":3000",
+		Bootstrap:    []string{},
+		AllowedPeers: []string{},
+		Mempool: Mempool{
+			MaxTxs: node.DefaultMaxMempoolTxs,
+		},
+		Log: Log{
+			Level:  "debug",
+			Format: "console",
+		},
+	}
+}
+
+/*
+Loads the configuration:
+ 1. Starts from the defaults
+ 2. Overrides them with the file in path, when there is one (the format is chosen by the extension)
+ 3. Overrides them with the BLOCKER_* environment variables
+ 4. Validates the result
+*/
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, fmt.Errorf("could not load config %s: %w", path, err)
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	case ".toml":
+		md, err := toml.Decode(string(b), c)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown field %s", undecoded[0])
+		}
+		return nil
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		return dec.Decode(c)
+	default:
+		return fmt.Errorf("unknown config format %q", ext)
+	}
+}
+
+// Environment variables that override the config, with the function that sets each one
+func (c *Config) envVars() map[string]func(string) error {
+	setString := func(field *string) func(string) error {
+		return func(v string) error {
+			*field = v
+			return nil
+		}
+	}
+	return map[string]func(string) error{
+		"LISTEN": setString(&c.Listen),
+		"BOOTSTRAP": func(v string) error {
+			c.Bootstrap = []string{}
+			if v != "" {
+				c.Bootstrap = strings.Split(v, ",")
+			}
+			return nil
+		},
+		"DATADIR":         setString(&c.DataDir),
+		"VALIDATOR_KEY":   setString(&c.ValidatorKey),
+		"GENESIS":         setString(&c.Genesis),
+		"PASSPHRASE_FILE": setString(&c.PassphraseFile),
+		"BLOCK_TIME": func(v string) error {
+			return c.BlockTime.UnmarshalText([]byte(v))
+		},
+		"HTTP_LISTEN":  setString(&c.HTTPListen),
+		"ADMIN_LISTEN": setString(&c.AdminListen),
+		"ADMIN_TOKEN":  setString(&c.AdminToken),
+		"ALLOWED_PEERS": func(v string) error {
+			c.AllowedPeers = []string{}
+			if v != "" {
+				c.AllowedPeers = strings.Split(v, ",")
+			}
+			return nil
+		},
+		"TLS_CERT": setString(&c.TLS.Cert),
+		"TLS_KEY":  setString(&c.TLS.Key),
+		"TLS_CA":   setString(&c.TLS.CA),
+		"TLS_REQUIRE_CLIENT_CERT": func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			c.TLS.RequireClientCert = b
+			return nil
+		},
+		"TLS_SERVER_NAME": setString(&c.TLS.ServerName),
+		"MEMPOOL_MAX_TXS": func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			c.Mempool.MaxTxs = n
+			return nil
+		},
+		"LOG_LEVEL":  setString(&c.Log.Level),
+		"LOG_FORMAT": setString(&c.Log.Format),
+	}
+}
+
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	for name, set := range c.envVars() {
+		v, ok := lookup(envPrefix + name)
+		if !ok {
+			continue
+		}
+		if err := set(v); err != nil {
+			return fmt.Errorf("invalid %s%s: %w", envPrefix, name, err)
+		}
+	}
+	return nil
+}
+
+// Verifies the values of the config, returning all the invalid ones
+func (c *Config) Validate() error {
+	errs := []error{}
+	if err := validateAddr(c.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("listen: %w", err))
+	}
+	for _, addr := range c.Bootstrap {
+		if err := validateAddr(addr); err != nil {
+			errs = append(errs, fmt.Errorf("bootstrap: %w", err))
+		}
+	}
+	if c.HTTPListen != "" {
+		if err := validateAddr(c.HTTPListen); err != nil {
+			errs = append(errs, fmt.Errorf("httpListen: %w", err))
+		}
+	}
+	if c.AdminListen != "" {
+		if err := validateAddr(c.AdminListen); err != nil {
+			errs = append(errs, fmt.Errorf("adminListen: %w", err))
+		}
+	}
+	for _, id := range c.AllowedPeers {
+		if b, err := hex.DecodeString(id); err != nil || len(b) != crypto.PubKeyLen {
+			errs = append(errs, fmt.Errorf("allowedPeers: invalid node ID %q", id))
+		}
+	}
+	if c.TLS.enabled() && (c.TLS.Cert == "" || c.TLS.Key == "") {
+		errs = append(errs, fmt.Errorf("tls: the cert and the key are required"))
+	}
+	if c.TLS.RequireClientCert && c.TLS.CA == "" {
+		errs = append(errs, fmt.Errorf("tls.requireClientCert: a CA is required to verify the client certificates"))
+	}
+	if c.BlockTime < 0 {
+		errs = append(errs, fmt.Errorf("blockTime: cannot be negative"))
+	}
+	if c.Mempool.MaxTxs <= 0 {
+		errs = append(errs, fmt.Errorf("mempool.maxTxs: must be positive"))
+	}
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if c.Log.Format != "console" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("log.format: must be console or json"))
+	}
+	return errors.Join(errs...)
+}
+
+func validateAddr(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return err
+	}
+	return nil
+}
+
+/*
+Builds the config of the node, reading the validator key and the files in the data dir.
+
+The identity key of the node is kept in the data dir (created in the first run), so the node keeps its ID between runs
+*/
+func (c *Config) ServerConfig() (node.ServerConfig, error) {
+	cfg := node.ServerConfig{
+		Version:         "Blocker-1",
+		ListenAddr:      c.Listen,
+		BootstrapNodes:  c.Bootstrap,
+		HTTPListenAddr:  c.HTTPListen,
+		AdminListenAddr: c.AdminListen,
+		AdminToken:      c.AdminToken,
+		AllowedPeers:    c.AllowedPeers,
+		BlockTime:       time.Duration(c.BlockTime),
+		MaxMempoolTxs:   c.Mempool.MaxTxs,
+		LogLevel:        c.Log.Level,
+		LogFormat:       c.Log.Format,
+	}
+	if c.TLS.enabled() {
+		cfg.TLS = &node.TLSConfig{
+			CertFile:          c.TLS.Cert,
+			KeyFile:           c.TLS.Key,
+			CAFile:            c.TLS.CA,
+			RequireClientCert: c.TLS.RequireClientCert,
+			ServerName:        c.TLS.ServerName,
+		}
+	}
+	if c.Genesis != "" {
+		genesis, err := node.LoadGenesis(c.Genesis)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Genesis = genesis
+	}
+	if c.ValidatorKey != "" {
+		passphrase, err := c.passphrase()
+		if err != nil {
+			return cfg, err
+		}
+		key, err := crypto.LoadKeyFile(c.ValidatorKey, passphrase)
+		if err != nil {
+			return cfg, fmt.Errorf("could not load validator key: %w", err)
+		}
+		cfg.PrivateKey = key
+	}
+	if c.DataDir != "" {
+		if err := os.MkdirAll(c.DataDir, 0o700); err != nil {
+			return cfg, err
+		}
+		key, err := loadNodeKey(filepath.Join(c.DataDir, nodeKeyFile))
+		if err != nil {
+			return cfg, err
+		}
+		cfg.NodeKey = key
+		cfg.BanListPath = filepath.Join(c.DataDir, banListFile)
+	}
+	return cfg, nil
+}
+
+// Reads the passphrase of the encrypted validator key from the passphrase file or from BLOCKER_PASSPHRASE
+func (c *Config) passphrase() (string, error) {
+	if c.PassphraseFile == "" {
+		return os.Getenv(envPrefix + "PASSPHRASE"), nil
+	}
+	b, err := os.ReadFile(c.PassphraseFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// Reads the identity key of the node, creating it when the file does not exist
+func loadNodeKey(path string) (*crypto.PrivateKey, error) {
+	key, err := crypto.ReadKeyFile(path)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	key = crypto.GeneratePrivateKey()
+	if err := crypto.WriteKeyFile(path, key); err != nil {
+		return nil, fmt.Errorf("could not write node key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/node"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestDefaultConfigIsDocumented(t *testing.T) {
+	cfg, err := Load("../config.example.yaml")
+	require.Nil(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoadFormats(t *testing.T) {
+	files := map[string]string{
+		"node.yaml": `
+listen: ":4000"
+bootstrap: [":3000"]
+blockTime: 2s
+mempool:
+  maxTxs: 50
+log:
+  level: info
+`,
+		"node.toml": `
+listen = ":4000"
+bootstrap = [":3000"]
+blockTime = "2s"
+[mempool]
+maxTxs = 50
+[log]
+level = "info"
+`,
+		"node.json": `{
+	"listen": ":4000",
+	"bootstrap": [":3000"],
+	"blockTime": "2s",
+	"mempool": {"maxTxs": 50},
+	"log": {"level": "info"}
+}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, name, content))
+			require.Nil(t, err)
+			assert.Equal(t, ":4000", cfg.Listen)
+			assert.Equal(t, []string{":3000"}, cfg.Bootstrap)
+			assert.Equal(t, util.Duration(2*time.Second), cfg.BlockTime)
+			assert.Equal(t, 50, cfg.Mempool.MaxTxs)
+			assert.Equal(t, "info", cfg.Log.Level)
+			assert.Equal(t, "console", cfg.Log.Format) // not in the file, keeps the default
+		})
+	}
+}
+
+func TestLoadUnknownField(t *testing.T) {
+	_, err := Load(writeConfig(t, "node.yaml", "listn: \":4000\"\n"))
+	assert.NotNil(t, err)
+	_, err = Load(writeConfig(t, "node.toml", "listn = \":4000\"\n"))
+	assert.NotNil(t, err)
+	_, err = Load(writeConfig(t, "node.json", `{"listn": ":4000"}`))
+	assert.NotNil(t, err)
+	_, err = Load(writeConfig(t, "node.ini", ""))
+	assert.NotNil(t, err)
+}
+
+func TestEnvOverrides(t *testing.T) {
+	t.Setenv("BLOCKER_LISTEN", ":5000")
+	t.Setenv("BLOCKER_BOOTSTRAP", ":3000,:4000")
+	t.Setenv("BLOCKER_BLOCK_TIME", "1s")
+	t.Setenv("BLOCKER_MEMPOOL_MAX_TXS", "7")
+	t.Setenv("BLOCKER_LOG_FORMAT", "json")
+	t.Setenv("BLOCKER_TLS_REQUIRE_CLIENT_CERT", "true")
+
+	cfg, err := Load(writeConfig(t, "node.yaml", "listen: \":4000\"\ntls: {cert: node.pem, key: node.key, ca: ca.pem}\n"))
+	require.Nil(t, err)
+	assert.Equal(t, ":5000", cfg.Listen)
+	assert.Equal(t, []string{":3000", ":4000"}, cfg.Bootstrap)
+	assert.Equal(t, util.Duration(time.Second), cfg.BlockTime)
+	assert.Equal(t, 7, cfg.Mempool.MaxTxs)
+	assert.Equal(t, "json", cfg.Log.Format)
+	assert.True(t, cfg.TLS.RequireClientCert)
+
+	t.Setenv("BLOCKER_MEMPOOL_MAX_TXS", "many")
+	_, err = Load("")
+	assert.ErrorContains(t, err, "BLOCKER_MEMPOOL_MAX_TXS")
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Listen = "3000"
+	cfg.Bootstrap = []string{":4000", "nowhere"}
+	cfg.BlockTime = -1
+	cfg.Mempool.MaxTxs = -1
+	cfg.Log.Level = "loud"
+	cfg.Log.Format = "xml"
+	cfg.AllowedPeers = []string{"node-1"}
+	cfg.TLS.CA = "ca.pem"
+	cfg.TLS.RequireClientCert = true
+
+	err := cfg.Validate()
+	require.NotNil(t, err)
+	for _, field := range []string{"listen", "bootstrap", "allowedPeers", "tls", "blockTime", "mempool.maxTxs", "log.level", "log.format"} {
+		assert.ErrorContains(t, err, field)
+	}
+	assert.Nil(t, Default().Validate())
+}
+
+func TestServerConfig(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		keyPath = filepath.Join(dir, "validator.key")
+		key     = crypto.GeneratePrivateKey()
+		cfg     = Default()
+	)
+	require.Nil(t, crypto.WriteKeyFile(keyPath, key))
+	cfg.ValidatorKey = keyPath
+	cfg.DataDir = filepath.Join(dir, "data")
+	cfg.BlockTime = util.Duration(time.Second)
+
+	server, err := cfg.ServerConfig()
+	require.Nil(t, err)
+	assert.Equal(t, key.Bytes(), server.PrivateKey.Bytes())
+	assert.Equal(t, time.Second, server.BlockTime)
+	assert.Equal(t, filepath.Join(cfg.DataDir, banListFile), server.BanListPath)
+
+	// the node keeps its identity key between runs
+	again, err := cfg.ServerConfig()
+	require.Nil(t, err)
+	assert.Equal(t, server.NodeKey.Bytes(), again.NodeKey.Bytes())
+}
+
+func TestServerConfigTLSAndAllowedPeers(t *testing.T) {
+	var (
+		cfg = Default()
+		id  = node.NodeIDFromPublicKey(crypto.GeneratePrivateKey().Public().Bytes())
+	)
+	server, err := cfg.ServerConfig()
+	require.Nil(t, err)
+	assert.Nil(t, server.TLS)
+
+	cfg.AllowedPeers = []string{id}
+	cfg.TLS = TLS{Cert: "node.pem", Key: "node.key", CA: "ca.pem", RequireClientCert: true}
+	require.Nil(t, cfg.Validate())
+	server, err = cfg.ServerConfig()
+	require.Nil(t, err)
+	assert.Equal(t, []string{id}, server.AllowedPeers)
+	assert.Equal(t, &node.TLSConfig{CertFile: "node.pem", KeyFile: "node.key", CAFile: "ca.pem", RequireClientCert: true}, server.TLS)
+}
+
+func TestServerConfigEncryptedKey(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		key      = crypto.GeneratePrivateKey()
+		passPath = writeConfig(t, "passphrase", "secret\n")
+		cfg      = Default()
+	)
+	ks, err := crypto.NewKeystore(dir)
+	require.Nil(t, err)
+	require.Nil(t, ks.Store("validator", key, "secret"))
+	cfg.ValidatorKey = filepath.Join(dir, "validator.json")
+
+	_, err = cfg.ServerConfig()
+	assert.ErrorIs(t, err, crypto.ErrWrongPassphrase)
+
+	cfg.PassphraseFile = passPath
+	server, err := cfg.ServerConfig()
+	require.Nil(t, err)
+	assert.Equal(t, key.Bytes(), server.PrivateKey.Bytes())
+}
@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Bech32 encoding (BIP-0173): a human readable prefix, the separator "1", the data in base32 and a 6 characters checksum
+const (
+	bech32Charset   = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Separator = '1'
+	bech32MaxLen    = 90
+	checksumLen     = 6
+)
+
+var (
+	ErrBech32Checksum = errors.New("invalid bech32 checksum")
+
+	bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+)
+
+// Encodes data (8 bits per byte) in bech32 with the prefix hrp
+func Bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	if len(hrp)+1+len(values)+checksumLen > bech32MaxLen {
+		return "", fmt.Errorf("bech32 string too long")
+	}
+	hrp = strings.ToLower(hrp)
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte(bech32Separator)
+	for _, v := range append(values, bech32Checksum(hrp, values)...) {
+		sb.WriteByte(bech32Charset[v])
+	}
+	return sb.String(), nil
+}
+
+/*
+Decodes a bech32 string, returning its prefix and data:
+ 1. Rejects mixed case strings and splits the prefix from the data at the last "1"
+ 2. Maps the data characters to 5 bits values and verifies the checksum
+ 3. Regroups the values (without the checksum) in bytes
+*/
+func Bech32Decode(s string) (string, []byte, error) {
+	if len(s) > bech32MaxLen {
+		return "", nil, fmt.Errorf("bech32 string too long")
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, fmt.Errorf("bech32 string with mixed case")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, bech32Separator)
+	if sep < 1 || sep+1+checksumLen > len(s) {
+		return "", nil, fmt.Errorf("invalid bech32 string %q", s)
+	}
+	hrp := s[:sep]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, fmt.Errorf("invalid character in bech32 prefix")
+		}
+	}
+	values := make([]byte, 0, len(s)-sep-1)
+	for _, c := range s[sep+1:] {
+		v := strings.IndexRune(bech32Charset, c)
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid bech32 character %q", c)
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(hrpExpand(hrp), values...)) != 1 {
+		return "", nil, ErrBech32Checksum
+	}
+	data, err := convertBits(values[:len(values)-checksumLen], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range bech32Generator {
+			if (top>>i)&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	return chk
+}
+
+// Expands the prefix for the checksum: high bits of each character, a zero, low bits of each character
+func hrpExpand(hrp string) []byte {
+	b := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		b = append(b, hrp[i]>>5)
+	}
+	b = append(b, 0)
+	for i := 0; i < len(hrp); i++ {
+		b = append(b, hrp[i]&31)
+	}
+	return b
+}
+
+func bech32Checksum(hrp string, values []byte) []byte {
+	polymod := bech32Polymod(append(append(hrpExpand(hrp), values...), make([]byte, checksumLen)...)) ^ 1
+	checksum := make([]byte, checksumLen)
+	for i := range checksum {
+		checksum[i] = byte(polymod>>(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// Regroups data from groups of `from` bits to groups of `to` bits, padding the last group with zeros if pad is set
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var (
+		acc    uint32
+		bits   uint
+		result []byte
+		maxv   = uint32(1)<<to - 1
+	)
+	for _, b := range data {
+		if uint32(b)>>from != 0 {
+			return nil, fmt.Errorf("invalid data value %d", b)
+		}
+		acc = acc<<from | uint32(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			result = append(result, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			result = append(result, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, fmt.Errorf("invalid padding in bech32 data")
+	}
+	return result, nil
+}
@@ -0,0 +1,118 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Offset of hardened child indexes. Ed25519 only supports hardened derivation
+	HardenedOffset uint32 = 0x80000000
+	// SLIP-44 coin type used in the default derivation paths
+	CoinType    uint32 = 1
+	slip10Curve        = "ed25519 seed"
+)
+
+/*
+Node of a SLIP-0010 ed25519 key tree.
+
+Every node is a 32 bytes private key seed plus a chain code, and any number of
+child keys can be derived from it. A single mnemonic therefore yields many PrivateKeys
+*/
+type HDKey struct {
+	key       []byte
+	chainCode []byte
+}
+
+// Master key of the tree rooted at a BIP39 seed
+func NewMasterKey(seed []byte) *HDKey {
+	return hmacKey([]byte(slip10Curve), seed)
+}
+
+/*
+Derives the hardened child `index` (the hardened offset is added if missing):
+HMAC-SHA512(chainCode, 0x00 || key || ser32(index))
+*/
+func (k *HDKey) Derive(index uint32) *HDKey {
+	if index < HardenedOffset {
+		index += HardenedOffset
+	}
+	data := make([]byte, 1+SeedLen+4)
+	copy(data[1:], k.key)
+	binary.BigEndian.PutUint32(data[1+SeedLen:], index)
+	return hmacKey(k.chainCode, data)
+}
+
+// Derives a key from a path such as m/44'/1'/0'/0'. Every segment must be hardened
+func (k *HDKey) DerivePath(path string) (*HDKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, index := range indexes {
+		key = key.Derive(index)
+	}
+	return key, nil
+}
+
+func (k *HDKey) PrivateKey() *PrivateKey {
+	return NewPrivateKeyFromSeed(k.key)
+}
+
+func (k *HDKey) ChainCode() []byte {
+	return k.chainCode
+}
+
+// Parses a derivation path into hardened child indexes
+func ParseDerivationPath(path string) ([]uint32, error) {
+	segments := strings.Split(strings.TrimSpace(path), "/")
+	if segments[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %q: must start with m", path)
+	}
+	indexes := make([]uint32, 0, len(segments)-1)
+	for _, s := range segments[1:] {
+		hardened := strings.HasSuffix(s, "'") || strings.HasSuffix(s, "H")
+		if !hardened {
+			return nil, fmt.Errorf("invalid derivation path %q: segment %q is not hardened (ed25519 only supports hardened keys)", path, s)
+		}
+		n, err := strconv.ParseUint(s[:len(s)-1], 10, 32)
+		if err != nil || uint32(n) >= HardenedOffset {
+			return nil, fmt.Errorf("invalid derivation path %q: bad index %q", path, s)
+		}
+		indexes = append(indexes, uint32(n)+HardenedOffset)
+	}
+	return indexes, nil
+}
+
+// Default path of the key `index` of an account: m/44'/<coin>'/<account>'/0'/<index>'
+func DerivationPath(account, index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'/0'/%d'", CoinType, account, index)
+}
+
+// Recovers the private key at `path` from a mnemonic and its (optional) passphrase
+func KeyFromMnemonic(mnemonic, passphrase, path string) (*PrivateKey, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := NewMasterKey(seed).DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey(), nil
+}
+
+func hmacKey(secret, data []byte) *HDKey {
+	mac := hmac.New(sha512.New, secret)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return &HDKey{
+		key:       sum[:SeedLen],
+		chainCode: sum[SeedLen:],
+	}
+}
@@ -0,0 +1,112 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test vectors of the BIP39 reference implementation (passphrase "TREZOR")
+func TestMnemonic(t *testing.T) {
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			entropy:  "8080808080808080808080808080808080808080808080808080808080808080",
+			mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless",
+			seed:     "c0c519bd0e91a2ed54357d9d1ebef6f5af218a153624cf4f2da911a0ed8f7a09e2ef61af0aca007096df430022f7a2b6fb91661a9589097069720d015e4e982f",
+		},
+		{
+			entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+			seed:     "dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+		},
+	}
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := EntropyToMnemonic(entropy)
+		require.NoError(t, err)
+		assert.Equal(t, v.mnemonic, mnemonic)
+
+		decoded, err := MnemonicToEntropy(mnemonic)
+		require.NoError(t, err)
+		assert.Equal(t, entropy, decoded)
+
+		seed, err := MnemonicToSeed(mnemonic, "TREZOR")
+		require.NoError(t, err)
+		assert.Equal(t, v.seed, hex.EncodeToString(seed))
+	}
+
+	mnemonic, err := NewMnemonic(DefaultEntropyBits)
+	require.NoError(t, err)
+	assert.NoError(t, ValidateMnemonic(mnemonic))
+
+	_, err = NewMnemonic(100)
+	assert.Error(t, err)
+	assert.ErrorIs(t, ValidateMnemonic("legal winner thank year wave sausage worth useful legal winner thank yellow yellow"), ErrInvalidMnemonic)
+	assert.ErrorIs(t, ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"), ErrChecksum)
+	assert.ErrorIs(t, ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandonn"), ErrInvalidMnemonic)
+}
+
+// test vector 1 of SLIP-0010 for ed25519
+func TestHDKeyDerivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	vectors := []struct {
+		path    string
+		private string
+		public  string
+	}{
+		{"m", "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7", "a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed"},
+		{"m/0'", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3", "8c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c"},
+		{"m/0'/1'/2'", "92a5b23c0b8a99e37d07df3fb9966917f5d06e02ddbd909c7e184371463e9fc9", "ae98736566d30ed0e9d2f4486a64bc95740d89c7db33f52121f8ea8f76ff0fc1"},
+		{"m/0H/1H/2H/2H/1000000000H", "8f94d394a8e8fd6b1bc2f3f49f5c47e385281d5c17e65324b0f62483e37e8793", "3c24da049451555d51a7014a37337aa4e12d41e485abccfa46b47dfb2af54b7a"},
+	}
+	master := NewMasterKey(seed)
+	for _, v := range vectors {
+		key, err := master.DerivePath(v.path)
+		require.NoError(t, err, v.path)
+		privKey := key.PrivateKey()
+		assert.Equal(t, v.private, hex.EncodeToString(privKey.Bytes()[:SeedLen]), v.path)
+		assert.Equal(t, v.public, hex.EncodeToString(privKey.Public().Bytes()), v.path)
+	}
+
+	// a non hardened index is hardened by Derive, but rejected in paths
+	assert.Equal(t, master.Derive(0), master.Derive(HardenedOffset))
+	_, err := master.DerivePath("m/0")
+	assert.Error(t, err)
+	_, err = master.DerivePath("0'/1'")
+	assert.Error(t, err)
+}
+
+func TestKeyFromMnemonic(t *testing.T) {
+	mnemonic, err := NewMnemonic(DefaultEntropyBits)
+	require.NoError(t, err)
+
+	first, err := KeyFromMnemonic(mnemonic, "", DerivationPath(0, 0))
+	require.NoError(t, err)
+	again, err := KeyFromMnemonic(mnemonic, "", DerivationPath(0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, first.Bytes(), again.Bytes())
+
+	second, err := KeyFromMnemonic(mnemonic, "", DerivationPath(0, 1))
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Bytes(), second.Bytes())
+
+	withPassphrase, err := KeyFromMnemonic(mnemonic, "secret", DerivationPath(0, 0))
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Bytes(), withPassphrase.Bytes())
+}
@@ -0,0 +1,27 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Reads a private key file, with the hex encoded seed of the key
+func ReadKeyFile(path string) (*PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(seed) != SeedLen {
+		return nil, fmt.Errorf("%s is not a valid key file", path)
+	}
+	return NewPrivateKeyFromSeed(seed), nil
+}
+
+// Writes the hex encoded seed of the key, readable only by the owner
+func WriteKeyFile(path string, key *PrivateKey) error {
+	seed := hex.EncodeToString(key.Bytes()[:SeedLen])
+	return os.WriteFile(path, []byte(seed+"\n"), 0o600)
+}
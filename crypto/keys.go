@@ -1,11 +1,15 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 const (
@@ -16,6 +20,17 @@ const (
 	AddressLen   = 20
 )
 
+// Derivation of the addresses
+const (
+	AddressVersion byte = 1 // version of the address derivation, hashed with the key
+	KeyTypeEd25519 byte = 1 // type of the keys, hashed with the key
+)
+
+// Bech32 prefix of the addresses of this network
+const AddressPrefix = "blk"
+
+var ErrAddressNetwork = errors.New("address of another network")
+
 // Private Key
 func GeneratePrivateKey() *PrivateKey {
 	seed := make([]byte, SeedLen)
@@ -90,12 +105,35 @@ func PublicKeyFromBytes(b []byte) *PublicKey {
 	}
 }
 
+/*
+Address of the key: the first 20 bytes of sha256(version || key type || public key).
+The version and key type are hashed with the key, so other derivations or key types never produce the same address
+*/
 func (p *PublicKey) Address() Address {
+	hash := sha256.Sum256(append([]byte{AddressVersion, KeyTypeEd25519}, p.key...))
+	return Address{
+		value: hash[:AddressLen],
+	}
+}
+
+/*
+Address used before the hashed addresses: the last 20 bytes of the public key.
+Outputs sent to legacy addresses can still be spent by their keys while the chain accepts them
+*/
+func (p *PublicKey) LegacyAddress() Address {
 	return Address{
 		value: p.key[len(p.key)-AddressLen:], // same as p.key[12:]. Ignores first 12 bytes and get last 20 to be address
 	}
 }
 
+// Verifies if the address belongs to the key, accepting its legacy address only if allowLegacy is set
+func (p *PublicKey) Owns(address []byte, allowLegacy bool) bool {
+	if bytes.Equal(p.Address().Bytes(), address) {
+		return true
+	}
+	return allowLegacy && bytes.Equal(p.LegacyAddress().Bytes(), address)
+}
+
 func (p *PublicKey) Bytes() []byte {
 	return p.key
 }
@@ -153,6 +191,40 @@ func (a Address) Bytes() []byte {
 	return a.value
 }
 
+// Bech32 encoding of the address with the prefix of the network, as shown to users (blk1...)
 func (a Address) String() string {
+	return a.Encode(AddressPrefix)
+}
+
+func (a Address) Encode(prefix string) string {
+	s, err := Bech32Encode(prefix, a.value)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Raw hex encoding of the address, without checksum
+func (a Address) Hex() string {
 	return hex.EncodeToString(a.value)
 }
+
+// Parses an address of this network encoded by Address.String
+func ParseAddress(s string) (Address, error) {
+	return ParseAddressWithPrefix(s, AddressPrefix)
+}
+
+// Parses a bech32 address, checking its checksum, its length and that it belongs to the network of the prefix
+func ParseAddressWithPrefix(s, prefix string) (Address, error) {
+	hrp, b, err := Bech32Decode(strings.TrimSpace(s))
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	if hrp != prefix {
+		return Address{}, fmt.Errorf("%w: address %q has prefix %q, expected %q", ErrAddressNetwork, s, hrp, prefix)
+	}
+	if len(b) != AddressLen {
+		return Address{}, fmt.Errorf("invalid address %q: %d bytes, expected %d", s, len(b), AddressLen)
+	}
+	return Address{value: b}, nil
+}
@@ -1,9 +1,13 @@
 package crypto
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGeneratePrivateKey(t *testing.T) {
@@ -15,13 +19,15 @@ func TestGeneratePrivateKey(t *testing.T) {
 
 func TestGeneratePrivateKeyFromString(t *testing.T) {
 	var (
-		seed         = "8e41a5878c3f70850588f6560c91048fa7d67743a148ddce23c1e47aeb149871"
-		expectedAddr = "3579839bce98bc81030b0ab5068e155e55bf222b"
-		privKey      = NewPrivateKeyFromString(seed)
+		seed               = "8e41a5878c3f70850588f6560c91048fa7d67743a148ddce23c1e47aeb149871"
+		expectedAddr       = "3579839bce98bc81030b0ab5068e155e55bf222b"
+		expectedHashedAddr = "222cf7b81e5cfa3b97147b277587e35272305890"
+		privKey            = NewPrivateKeyFromString(seed)
 	)
 	assert.Equal(t, PrivKeyLen, len(privKey.Bytes()))
 	address := privKey.Public().Address()
-	assert.Equal(t, expectedAddr, address.String())
+	assert.Equal(t, expectedAddr, privKey.Public().LegacyAddress().Hex())
+	assert.Equal(t, expectedHashedAddr, address.Hex())
 }
 
 func TestPrivateKeySign(t *testing.T) {
@@ -51,4 +57,62 @@ func TestPublicKeyToAddress(t *testing.T) {
 	pubKey := privKey.Public()
 	address := pubKey.Address()
 	assert.Equal(t, AddressLen, len(address.Bytes()))
+	// the address is a hash, it does not reveal the public key
+	assert.NotEqual(t, pubKey.Bytes()[PubKeyLen-AddressLen:], address.Bytes())
+
+	assert.True(t, pubKey.Owns(address.Bytes(), false))
+	assert.False(t, pubKey.Owns(pubKey.LegacyAddress().Bytes(), false))
+	assert.True(t, pubKey.Owns(pubKey.LegacyAddress().Bytes(), true))
+	assert.False(t, pubKey.Owns(GeneratePrivateKey().Public().Address().Bytes(), true))
+}
+
+func TestParseAddress(t *testing.T) {
+	// BIP-0173 test vectors
+	for _, s := range []string{"A12UEL5L", "an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw"} {
+		_, _, err := Bech32Decode(s)
+		assert.NoError(t, err, s)
+	}
+	_, _, err := Bech32Decode("A12UEL5M")
+	assert.ErrorIs(t, err, ErrBech32Checksum)
+
+	var (
+		address = GeneratePrivateKey().Public().Address()
+		encoded = address.String()
+	)
+	assert.True(t, strings.HasPrefix(encoded, AddressPrefix+"1"))
+	parsed, err := ParseAddress(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, address.Bytes(), parsed.Bytes())
+	parsed, err = ParseAddress(strings.ToUpper(encoded))
+	require.NoError(t, err)
+	assert.Equal(t, address.Bytes(), parsed.Bytes())
+
+	// a typo breaks the checksum
+	typo := []byte(encoded)
+	typo[10] = map[bool]byte{true: 'q', false: 'p'}[typo[10] != 'q']
+	_, err = ParseAddress(string(typo))
+	assert.ErrorIs(t, err, ErrBech32Checksum)
+
+	_, err = ParseAddress(address.Encode("tblk"))
+	assert.ErrorIs(t, err, ErrAddressNetwork)
+	_, err = ParseAddress(address.Hex())
+	assert.Error(t, err)
+	short, _ := Bech32Encode(AddressPrefix, address.Bytes()[:10])
+	_, err = ParseAddress(short)
+	assert.Error(t, err)
+}
+
+func TestKeyFile(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "test.key")
+		key  = GeneratePrivateKey()
+	)
+	require.Nil(t, WriteKeyFile(path, key))
+	read, err := ReadKeyFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, key.Bytes(), read.Bytes())
+
+	require.Nil(t, os.WriteFile(path, []byte("not a key"), 0o600))
+	_, err = ReadKeyFile(path)
+	assert.NotNil(t, err)
 }
@@ -0,0 +1,313 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keystoreVersion = 1
+	keystoreExt     = ".json"
+	scryptR         = 8
+	scryptP         = 1
+	scryptKeyLen    = 32 // AES-256
+	saltLen         = 32
+	// limits of the parameters read from encrypted keys, so a crafted file cannot exhaust the memory or the CPU
+	maxScryptMemory = 1 << 30 // bytes used by the derivation: 128 * N * R
+	maxScryptP      = 16
+)
+
+// cost of the key derivation of new encrypted keys (existing keys keep the cost they were encrypted with)
+var scryptN = 1 << 15
+
+var (
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted key")
+	ErrKeyNotFound     = errors.New("key not found")
+	ErrKeyExists       = errors.New("key already exists")
+
+	keyNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
+
+/*
+Private key encrypted with a passphrase, as written to disk.
+
+The seed of the key is encrypted with AES-256-GCM, using a key derived from the passphrase with scrypt.
+The public key and address are kept in clear (and authenticated by the encryption), so keys can be listed without the passphrase
+*/
+type EncryptedKey struct {
+	Version   int          `json:"version"`
+	Address   string       `json:"address"`
+	PublicKey string       `json:"publicKey"`
+	Crypto    cryptoParams `json:"crypto"`
+}
+
+type cryptoParams struct {
+	KDF        string       `json:"kdf"`
+	KDFParams  scryptParams `json:"kdfParams"`
+	Cipher     string       `json:"cipher"`
+	Nonce      string       `json:"nonce"`
+	Ciphertext string       `json:"ciphertext"`
+}
+
+type scryptParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+// Encrypts the private key with the passphrase, returning its JSON encoding
+func EncryptKey(key *PrivateKey, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	params := scryptParams{N: scryptN, R: scryptR, P: scryptP, Salt: hex.EncodeToString(salt)}
+	aead, err := params.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	pubKey := key.Public().Bytes()
+	ek := EncryptedKey{
+		Version:   keystoreVersion,
+		Address:   key.Public().Address().String(),
+		PublicKey: hex.EncodeToString(pubKey),
+		Crypto: cryptoParams{
+			KDF:        "scrypt",
+			KDFParams:  params,
+			Cipher:     "aes-256-gcm",
+			Nonce:      hex.EncodeToString(nonce),
+			Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, key.Bytes()[:SeedLen], pubKey)),
+		},
+	}
+	return json.MarshalIndent(ek, "", "  ")
+}
+
+// Decrypts a key encrypted by EncryptKey
+func DecryptKey(data []byte, passphrase string) (*PrivateKey, error) {
+	ek, err := parseEncryptedKey(data)
+	if err != nil {
+		return nil, err
+	}
+	if ek.Crypto.KDF != "scrypt" || ek.Crypto.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported key encryption %s/%s", ek.Crypto.KDF, ek.Crypto.Cipher)
+	}
+	pubKey, err := hex.DecodeString(ek.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in encrypted key")
+	}
+	nonce, err := hex.DecodeString(ek.Crypto.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce in encrypted key")
+	}
+	ciphertext, err := hex.DecodeString(ek.Crypto.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext in encrypted key")
+	}
+	aead, err := ek.Crypto.KDFParams.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce in encrypted key")
+	}
+	seed, err := aead.Open(nil, nonce, ciphertext, pubKey)
+	if err != nil || len(seed) != SeedLen {
+		return nil, ErrWrongPassphrase
+	}
+	key := NewPrivateKeyFromSeed(seed)
+	if !bytes.Equal(key.Public().Bytes(), pubKey) {
+		return nil, ErrWrongPassphrase
+	}
+	return key, nil
+}
+
+func parseEncryptedKey(data []byte) (*EncryptedKey, error) {
+	ek := &EncryptedKey{}
+	if err := json.Unmarshal(data, ek); err != nil {
+		return nil, fmt.Errorf("invalid encrypted key: %w", err)
+	}
+	if ek.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported encrypted key version %d", ek.Version)
+	}
+	return ek, nil
+}
+
+// Derives the encryption key from the passphrase
+func (p scryptParams) aead(passphrase string) (cipher.AEAD, error) {
+	if p.N < 2 || p.R < 1 || p.P < 1 || p.P > maxScryptP || 128*int64(p.N)*int64(p.R) > maxScryptMemory {
+		return nil, fmt.Errorf("unsupported scrypt parameters n=%d r=%d p=%d", p.N, p.R, p.P)
+	}
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt in encrypted key")
+	}
+	derived, err := scrypt.Key([]byte(passphrase), salt, p.N, p.R, p.P, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+/*
+Reads a key file, which can be a plain key file (hex encoded seed) or an encrypted key.
+The passphrase is only used for encrypted keys
+*/
+func LoadKeyFile(path, passphrase string) (*PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncryptedKey(b) {
+		return ReadKeyFile(path)
+	}
+	return DecryptKey(b, passphrase)
+}
+
+// Verifies if the content of a key file is an encrypted key
+func IsEncryptedKey(data []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(data)), "{")
+}
+
+// Directory of encrypted keys, one file per key named by the key name
+type Keystore struct {
+	dir string
+}
+
+// Information of a stored key, available without the passphrase
+type KeyInfo struct {
+	Name      string
+	Address   string
+	PublicKey string
+}
+
+func NewKeystore(dir string) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Keystore{dir: dir}, nil
+}
+
+func (ks *Keystore) path(name string) (string, error) {
+	if !keyNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid key name %q (only letters, digits, - and _)", name)
+	}
+	return filepath.Join(ks.dir, name+keystoreExt), nil
+}
+
+// Encrypts the key with the passphrase and saves it with the name, which must not be in use
+func (ks *Keystore) Store(name string, key *PrivateKey, passphrase string) error {
+	data, err := EncryptKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	return ks.write(name, data)
+}
+
+func (ks *Keystore) write(name string, data []byte) error {
+	path, err := ks.path(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrKeyExists, name)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+func (ks *Keystore) Load(name, passphrase string) (*PrivateKey, error) {
+	data, err := ks.Export(name)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKey(data, passphrase)
+}
+
+// Returns the encrypted key, to be imported in another keystore
+func (ks *Keystore) Export(name string) ([]byte, error) {
+	path, err := ks.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	return data, err
+}
+
+// Saves an encrypted key exported from another keystore, verifying that the passphrase decrypts it
+func (ks *Keystore) Import(name string, data []byte, passphrase string) (*PrivateKey, error) {
+	key, err := DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.write(name, data); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Lists the stored keys sorted by name
+func (ks *Keystore) List() ([]KeyInfo, error) {
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return nil, err
+	}
+	keys := []KeyInfo{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), keystoreExt)
+		if entry.IsDir() || !ok || !keyNameRegexp.MatchString(name) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(ks.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		ek, err := parseEncryptedKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		pubKey, err := hex.DecodeString(ek.PublicKey)
+		if err != nil || len(pubKey) != PubKeyLen {
+			return nil, fmt.Errorf("%s: invalid public key in encrypted key", entry.Name())
+		}
+		// the address is derived again, so keys stored with hex addresses are listed in the current encoding
+		keys = append(keys, KeyInfo{Name: name, Address: PublicKeyFromBytes(pubKey).Address().String(), PublicKey: ek.PublicKey})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Name < keys[j].Name
+	})
+	return keys, nil
+}
@@ -0,0 +1,115 @@
+package crypto
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	scryptN = 1 << 10 // keeps the tests fast
+}
+
+func TestEncryptKey(t *testing.T) {
+	key := GeneratePrivateKey()
+	data, err := EncryptKey(key, "correct horse")
+	require.Nil(t, err)
+	assert.True(t, IsEncryptedKey(data))
+	assert.NotContains(t, string(data), string(key.Bytes()[:SeedLen]))
+
+	decrypted, err := DecryptKey(data, "correct horse")
+	require.Nil(t, err)
+	assert.Equal(t, key.Bytes(), decrypted.Bytes())
+
+	_, err = DecryptKey(data, "wrong horse")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+	// the public key is authenticated: it cannot be replaced by the key of someone else
+	ek := &EncryptedKey{}
+	require.Nil(t, json.Unmarshal(data, ek))
+	other, err := EncryptKey(GeneratePrivateKey(), "correct horse")
+	require.Nil(t, err)
+	otherEK := &EncryptedKey{}
+	require.Nil(t, json.Unmarshal(other, otherEK))
+	ek.PublicKey = otherEK.PublicKey
+	tampered, err := json.Marshal(ek)
+	require.Nil(t, err)
+	_, err = DecryptKey(tampered, "correct horse")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+	// a file asking for a derivation too costly is rejected before deriving the key
+	require.Nil(t, json.Unmarshal(data, ek))
+	ek.Crypto.KDFParams.N = 1 << 30
+	costly, err := json.Marshal(ek)
+	require.Nil(t, err)
+	_, err = DecryptKey(costly, "correct horse")
+	assert.ErrorContains(t, err, "unsupported scrypt parameters")
+}
+
+func TestKeystore(t *testing.T) {
+	ks, err := NewKeystore(filepath.Join(t.TempDir(), "keys"))
+	require.Nil(t, err)
+	var (
+		validator = GeneratePrivateKey()
+		wallet    = GeneratePrivateKey()
+	)
+	require.Nil(t, ks.Store("wallet", wallet, "pass1"))
+	require.Nil(t, ks.Store("validator", validator, "pass2"))
+	assert.ErrorIs(t, ks.Store("wallet", GeneratePrivateKey(), "pass1"), ErrKeyExists)
+	assert.NotNil(t, ks.Store("../escape", GeneratePrivateKey(), "pass1"))
+
+	keys, err := ks.List()
+	require.Nil(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "validator", keys[0].Name)
+	assert.Equal(t, validator.Public().Address().String(), keys[0].Address)
+	assert.Equal(t, "wallet", keys[1].Name)
+
+	loaded, err := ks.Load("validator", "pass2")
+	require.Nil(t, err)
+	assert.Equal(t, validator.Bytes(), loaded.Bytes())
+	_, err = ks.Load("validator", "pass1")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+	_, err = ks.Load("missing", "pass1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// moves the wallet key to another keystore
+	exported, err := ks.Export("wallet")
+	require.Nil(t, err)
+	other, err := NewKeystore(t.TempDir())
+	require.Nil(t, err)
+	_, err = other.Import("wallet", exported, "wrong")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+	imported, err := other.Import("wallet", exported, "pass1")
+	require.Nil(t, err)
+	assert.Equal(t, wallet.Bytes(), imported.Bytes())
+
+	info, err := os.Stat(filepath.Join(ks.dir, "wallet.json"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	var (
+		dir       = t.TempDir()
+		key       = GeneratePrivateKey()
+		plain     = filepath.Join(dir, "plain.key")
+		encrypted = filepath.Join(dir, "encrypted.json")
+	)
+	require.Nil(t, WriteKeyFile(plain, key))
+	data, err := EncryptKey(key, "pass")
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(encrypted, data, 0o600))
+
+	for _, path := range []string{plain, encrypted} {
+		loaded, err := LoadKeyFile(path, "pass")
+		require.Nil(t, err)
+		assert.Equal(t, key.Bytes(), loaded.Bytes())
+	}
+	_, err = LoadKeyFile(encrypted, "")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+}
@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// BIP39 parameters
+const (
+	MinEntropyBits     = 128
+	MaxEntropyBits     = 256
+	DefaultEntropyBits = 256 // 24 words
+	mnemonicSeedRounds = 2048
+	mnemonicSeedLen    = 64
+)
+
+var (
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	ErrChecksum        = errors.New("invalid mnemonic checksum")
+
+	//go:embed wordlist_english.txt
+	englishWords string
+
+	wordList  = strings.Fields(englishWords)
+	wordIndex = func() map[string]int {
+		m := make(map[string]int, len(wordList))
+		for i, w := range wordList {
+			m[w] = i
+		}
+		return m
+	}()
+)
+
+// Generates a new mnemonic from `bits` of random entropy (128 to 256, multiple of 32)
+func NewMnemonic(bits int) (string, error) {
+	if err := validateEntropyBits(bits); err != nil {
+		return "", err
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := io.ReadFull(rand.Reader, entropy); err != nil {
+		return "", err
+	}
+	return EntropyToMnemonic(entropy)
+}
+
+/*
+Encodes entropy as a BIP39 mnemonic (english word list).
+
+1. Appends the first len(entropy)/4 bits of sha256(entropy) as checksum
+2. Splits the bits in groups of 11, each one being the index of a word
+*/
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if err := validateEntropyBits(bits); err != nil {
+		return "", err
+	}
+	checksum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), checksum[0])
+
+	words := make([]string, (bits+bits/32)/11)
+	for i := range words {
+		idx := 0
+		for j := 0; j < 11; j++ {
+			idx = idx<<1 | bit(data, i*11+j)
+		}
+		words[i] = wordList[idx]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// Decodes a mnemonic back to its entropy, verifying the words and the checksum
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("%w: expected 12, 15, 18, 21 or 24 words, got %d", ErrInvalidMnemonic, len(words))
+	}
+
+	totalBits := len(words) * 11
+	checksumBits := totalBits / 33
+	data := make([]byte, (totalBits+7)/8)
+	for i, w := range words {
+		idx, ok := wordIndex[w]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, w)
+		}
+		for j := 0; j < 11; j++ {
+			if idx&(1<<(10-j)) != 0 {
+				pos := i*11 + j
+				data[pos/8] |= 1 << (7 - pos%8)
+			}
+		}
+	}
+
+	entropy := data[:(totalBits-checksumBits)/8]
+	checksum := sha256.Sum256(entropy)
+	if checksum[0]>>(8-checksumBits) != data[len(entropy)]>>(8-checksumBits) {
+		return nil, ErrChecksum
+	}
+	return entropy, nil
+}
+
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+/*
+Derives the 64 bytes BIP39 seed of a mnemonic: PBKDF2-HMAC-SHA512 with 2048 rounds and "mnemonic"+passphrase as salt.
+The mnemonic is validated first, so a typo can't silently yield another wallet
+*/
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+	return pbkdf2.Key([]byte(normalized), []byte(salt), mnemonicSeedRounds, mnemonicSeedLen, sha512.New), nil
+}
+
+func validateEntropyBits(bits int) error {
+	if bits < MinEntropyBits || bits > MaxEntropyBits || bits%32 != 0 {
+		return fmt.Errorf("invalid entropy size %d bits, must be a multiple of 32 between %d and %d", bits, MinEntropyBits, MaxEntropyBits)
+	}
+	return nil
+}
+
+// value (0 or 1) of the n-th bit of b, most significant first
+func bit(b []byte, n int) int {
+	return int(b[n/8]>>(7-n%8)) & 1
+}
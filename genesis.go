@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/node"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/CaiqueRibeiro/blocker/util"
+)
+
+/*
+Writes a genesis specification and prints the hash of its genesis block.
+
+The timestamp is written to the file, so every node started with the file creates the same genesis block
+*/
+func runGenesisInit(args []string) error {
+	var (
+		fs          = newFlagSet("genesis init")
+		out         = fs.String("out", "genesis.json", "genesis file to write")
+		chainID     = fs.String("chain-id", node.DefaultChainID, "ID of the chain")
+		timestamp   = fs.String("timestamp", "", "timestamp of the genesis block (RFC 3339). Empty uses the current time")
+		blockTime   = fs.Duration("block-time", node.BLOCK_TIME, "time between blocks")
+		pow         = fs.Bool("pow", false, "mine the blocks with proof-of-work instead of creating them with validators")
+		powBits     = fs.String("pow-bits", fmt.Sprintf("%08x", node.DefaultPowBits), "proof-of-work target of the genesis block, in compact format (hex)")
+		retarget    = fs.Int("retarget-interval", node.DefaultRetargetInterval, "blocks between the adjustments of the proof-of-work target")
+		epochLength = fs.Int("epoch-length", 0, "blocks of an epoch of validator set changes made by transactions. Zero keeps the initial validators")
+		minStake    = fs.Int64("min-stake", 0, "coins locked by a validator joining the set")
+		allocations = []node.Allocation{}
+		validators  = []string{}
+	)
+	fs.Func("alloc", "allocation in the format address=amount (repeatable)", func(v string) error {
+		address, amount, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("allocation must be address=amount")
+		}
+		n, err := strconv.ParseInt(amount, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount %q", amount)
+		}
+		allocations = append(allocations, node.Allocation{Address: address, Amount: n})
+		return nil
+	})
+	fs.Func("validator", "hex encoded public key of an initial validator (repeatable)", func(v string) error {
+		validators = append(validators, v)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	genesis := &node.Genesis{
+		ChainID:     *chainID,
+		Timestamp:   time.Now().UTC().Truncate(time.Second),
+		Allocations: allocations,
+		Validators:  validators,
+		BlockTime:   util.Duration(*blockTime),
+	}
+	if *pow {
+		bits, err := strconv.ParseUint(*powBits, 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid proof-of-work bits %q", *powBits)
+		}
+		genesis.ProofOfWork = &node.ProofOfWorkParams{Bits: uint32(bits), RetargetInterval: *retarget}
+	}
+	if *epochLength > 0 {
+		genesis.Staking = &node.StakingParams{EpochLength: *epochLength, MinStake: *minStake}
+	}
+	if *timestamp != "" {
+		t, err := time.Parse(time.RFC3339, *timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", *timestamp)
+		}
+		genesis.Timestamp = t.UTC()
+	}
+	block, err := genesis.Block()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("%s already exists", *out)
+	}
+	if err := genesis.Save(*out); err != nil {
+		return err
+	}
+	fmt.Println("genesis file:", *out)
+	fmt.Println("chain id:    ", genesis.ChainID)
+	fmt.Println("block hash:  ", hex.EncodeToString(types.HashBlock(block)))
+	return nil
+}
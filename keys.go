@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+)
+
+// Generates a private key, writing it to a key file (or printing it) with its public key and address
+func runKeygen(args []string) error {
+	var (
+		fs  = newFlagSet("keygen")
+		out = fs.String("out", "", "key file to write. Empty prints the private key")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	key := crypto.GeneratePrivateKey()
+	if *out != "" {
+		if _, err := os.Stat(*out); err == nil {
+			return fmt.Errorf("%s already exists", *out)
+		}
+		if err := crypto.WriteKeyFile(*out, key); err != nil {
+			return err
+		}
+		fmt.Println("key file:   ", *out)
+	} else {
+		fmt.Println("private key:", hex.EncodeToString(key.Bytes()[:crypto.SeedLen]))
+	}
+	fmt.Println("public key: ", hex.EncodeToString(key.Public().Bytes()))
+	fmt.Println("address:    ", key.Public().Address())
+	return nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"golang.org/x/term"
+)
+
+const (
+	defaultKeystore = "keystore"
+	passphraseEnv   = "BLOCKER_PASSPHRASE"
+)
+
+// Reader of the standard input shared by the prompts, so the lines buffered by one prompt are not lost to the next one
+var stdin = bufio.NewReader(os.Stdin)
+
+// Flags shared by the keys commands
+func keystoreFlags(fs *flag.FlagSet) (dir, passphraseFile *string) {
+	dir = fs.String("keystore", defaultKeystore, "directory of the encrypted keys")
+	passphraseFile = fs.String("passphrase-file", "", "file with the passphrase. Empty reads "+passphraseEnv+" or asks for it")
+	return dir, passphraseFile
+}
+
+/*
+Returns the passphrase of a key, from the passphrase file, from the BLOCKER_PASSPHRASE variable
+or asking for it in the standard input, in this order. A passphrase typed in a terminal is not echoed
+*/
+func readPassphrase(passphraseFile, prompt string) (string, error) {
+	if passphraseFile != "" {
+		b, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	if v, ok := os.LookupEnv(passphraseEnv); ok {
+		return v, nil
+	}
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt+": ")
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("could not read passphrase: %w", err)
+		}
+		return string(b), nil
+	}
+	return readLine(prompt)
+}
+
+// Asks for a line in the standard input
+func readLine(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt+": ")
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("could not read %s: %w", prompt, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Reads a plain or encrypted key file, asking for the passphrase only when the key is encrypted
+func loadKey(path, passphraseFile string) (*crypto.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !crypto.IsEncryptedKey(b) {
+		return crypto.ReadKeyFile(path)
+	}
+	passphrase, err := readPassphrase(passphraseFile, "passphrase of "+path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecryptKey(b, passphrase)
+}
+
+// Flags selecting the key derived from a mnemonic
+func derivationFlags(fs *flag.FlagSet) (account, index *uint) {
+	account = fs.Uint("account", 0, "account of the key derived from the mnemonic")
+	index = fs.Uint("index", 0, "index of the key derived from the mnemonic")
+	return account, index
+}
+
+/*
+Generates a key and stores it encrypted in the keystore.
+With --mnemonic the key is derived from a new mnemonic, which is printed once so it can be written down
+*/
+func runKeysNew(args []string) error {
+	var (
+		fs                  = newFlagSet("keys new")
+		name                = fs.String("name", "", "name of the key")
+		mnemonic            = fs.Bool("mnemonic", false, "derives the key from a new mnemonic phrase")
+		account, index      = derivationFlags(fs)
+		dir, passphraseFile = keystoreFlags(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*mnemonic {
+		return storeKey(*dir, *name, *passphraseFile, crypto.GeneratePrivateKey())
+	}
+	phrase, err := crypto.NewMnemonic(crypto.DefaultEntropyBits)
+	if err != nil {
+		return err
+	}
+	key, err := crypto.KeyFromMnemonic(phrase, "", crypto.DerivationPath(uint32(*account), uint32(*index)))
+	if err != nil {
+		return err
+	}
+	if err := storeKey(*dir, *name, *passphraseFile, key); err != nil {
+		return err
+	}
+	fmt.Println("mnemonic:", phrase)
+	fmt.Println("write the mnemonic down and keep it safe, it recovers every key of this wallet")
+	return nil
+}
+
+// Recovers a key from a mnemonic (read from --mnemonic-file or the standard input) and stores it in the keystore
+func runKeysRecover(args []string) error {
+	var (
+		fs                  = newFlagSet("keys recover")
+		name                = fs.String("name", "", "name of the key")
+		mnemonicFile        = fs.String("mnemonic-file", "", "file with the mnemonic. Empty asks for it")
+		account, index      = derivationFlags(fs)
+		dir, passphraseFile = keystoreFlags(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var phrase string
+	if *mnemonicFile != "" {
+		b, err := os.ReadFile(*mnemonicFile)
+		if err != nil {
+			return err
+		}
+		phrase = string(b)
+	} else {
+		line, err := readLine("mnemonic")
+		if err != nil {
+			return err
+		}
+		phrase = line
+	}
+	key, err := crypto.KeyFromMnemonic(phrase, "", crypto.DerivationPath(uint32(*account), uint32(*index)))
+	if err != nil {
+		return err
+	}
+	return storeKey(*dir, *name, *passphraseFile, key)
+}
+
+// Lists the keys of the keystore, without decrypting them
+func runKeysList(args []string) error {
+	var (
+		fs     = newFlagSet("keys list")
+		dir, _ = keystoreFlags(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ks, err := crypto.NewKeystore(*dir)
+	if err != nil {
+		return err
+	}
+	keys, err := ks.List()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS\tPUBLIC KEY")
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key.Name, key.Address, key.PublicKey)
+	}
+	return w.Flush()
+}
+
+/*
+Imports a key into the keystore: an encrypted key exported from another keystore (kept with its passphrase)
+or a plain key file (encrypted with a new passphrase)
+*/
+func runKeysImport(args []string) error {
+	var (
+		fs                  = newFlagSet("keys import")
+		name                = fs.String("name", "", "name of the key")
+		file                = fs.String("file", "", "encrypted key or plain key file to import")
+		dir, passphraseFile = keystoreFlags(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	b, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	if !crypto.IsEncryptedKey(b) {
+		key, err := crypto.ReadKeyFile(*file)
+		if err != nil {
+			return err
+		}
+		return storeKey(*dir, *name, *passphraseFile, key)
+	}
+	ks, err := crypto.NewKeystore(*dir)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(*passphraseFile, "passphrase of the key")
+	if err != nil {
+		return err
+	}
+	key, err := ks.Import(*name, b, passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Println("imported:", *name)
+	fmt.Println("address: ", key.Public().Address())
+	return nil
+}
+
+// Exports a key of the keystore encrypted (to be imported in another keystore) or, with --plain, as a plain key file
+func runKeysExport(args []string) error {
+	var (
+		fs                  = newFlagSet("keys export")
+		name                = fs.String("name", "", "name of the key")
+		out                 = fs.String("out", "", "file to write")
+		plain               = fs.Bool("plain", false, "writes the key decrypted, as a plain key file")
+		dir, passphraseFile = keystoreFlags(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("the output file is required")
+	}
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("%s already exists", *out)
+	}
+	ks, err := crypto.NewKeystore(*dir)
+	if err != nil {
+		return err
+	}
+	if !*plain {
+		b, err := ks.Export(*name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(*out, b, 0o600)
+	}
+	passphrase, err := readPassphrase(*passphraseFile, "passphrase of "+*name)
+	if err != nil {
+		return err
+	}
+	key, err := ks.Load(*name, passphrase)
+	if err != nil {
+		return err
+	}
+	return crypto.WriteKeyFile(*out, key)
+}
+
+func storeKey(dir, name, passphraseFile string, key *crypto.PrivateKey) error {
+	if name == "" {
+		return fmt.Errorf("the key name is required")
+	}
+	ks, err := crypto.NewKeystore(dir)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(passphraseFile, "passphrase of the new key")
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return errors.New("the passphrase cannot be empty")
+	}
+	if err := ks.Store(name, key, passphrase); err != nil {
+		return err
+	}
+	fmt.Println("stored:  ", name)
+	fmt.Println("address: ", key.Public().Address())
+	return nil
+}
@@ -2,69 +2,118 @@ package main
 
 import (
 	"context"
-	"log"
-	"time"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
 
-	"github.com/CaiqueRibeiro/blocker/crypto"
 	"github.com/CaiqueRibeiro/blocker/node"
-	"github.com/CaiqueRibeiro/blocker/proto"
-	"github.com/CaiqueRibeiro/blocker/util"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
-func main() {
-	makeNode(":3000", []string{}, true) // creates a genesis node
-	time.Sleep(time.Second)
-	makeNode(":4000", []string{":3000"}, false) // creates a node that connects to the genesis node
-	time.Sleep(time.Second)
-	makeNode(":6000", []string{":4000"}, false) // creates a node that connects to the genesis node
+const usage = `blocker - UTXO blockchain node
+
+Usage:
+  blocker node run [flags]        runs a node
+  blocker keygen [flags]          generates a private key
+  blocker keys new [flags]        generates a key in the encrypted keystore
+  blocker keys recover [flags]    recovers a key from a mnemonic into the keystore
+  blocker keys list [flags]       lists the keys of the keystore
+  blocker keys import [flags]     imports a key into the keystore
+  blocker keys export [flags]     exports a key of the keystore
+  blocker tx send [flags]         sends coins to an address
+  blocker chain get-block [flags] prints a block of the chain
+  blocker peers [flags]           lists the peers connected to a node (admin service)
+  blocker genesis init [flags]    writes a genesis specification
+  blocker sim                     runs a local simulation with three nodes
+
+Run "blocker <command> -h" to see the flags of a command
+`
+
+// commands by name, with the subcommand when the command has one ("tx send")
+var commands = map[string]func(args []string) error{
+	"node run":        runNode,
+	"keygen":          runKeygen,
+	"keys new":        runKeysNew,
+	"keys recover":    runKeysRecover,
+	"keys list":       runKeysList,
+	"keys import":     runKeysImport,
+	"keys export":     runKeysExport,
+	"tx send":         runTxSend,
+	"chain get-block": runGetBlock,
+	"peers":           runPeers,
+	"genesis init":    runGenesisInit,
+	"sim":             runSim,
+}
 
-	for {
-		time.Sleep(time.Second)
-		makeTransaction()
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
 }
 
-func makeNode(listenAddr string, bootstrapNodes []string, isValidator bool) *node.Node {
-	cfg := node.ServerConfig{
-		Version:    "Blocker-1",
-		ListenAddr: listenAddr,
+func run(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Print(usage)
+		return nil
+	}
+	if len(args) > 1 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd(args[2:])
+		}
 	}
-	if isValidator {
-		cfg.PrivateKey = crypto.GeneratePrivateKey()
+	if cmd, ok := commands[args[0]]; ok {
+		return cmd(args[1:])
 	}
-	n := node.NewNode(cfg)                 // creates a new node
-	go n.Start(listenAddr, bootstrapNodes) // starts the node
-	return n
+	fmt.Fprint(os.Stderr, usage)
+	return fmt.Errorf("unknown command %q", strings.Join(args[:min(len(args), 2)], " "))
 }
 
-// temporary: just to test gRPC calls
-func makeTransaction() {
-	client, err := grpc.Dial(":3000", grpc.WithInsecure())
-	if err != nil {
-		log.Fatal(err)
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("blocker "+name, flag.ContinueOnError)
+}
+
+// Flags of the TLS used to connect to a node
+type tlsFlags struct {
+	enabled bool
+	config  node.TLSConfig
+}
+
+func addTLSFlags(fs *flag.FlagSet) *tlsFlags {
+	f := &tlsFlags{}
+	fs.BoolVar(&f.enabled, "tls", false, "connects to the node with TLS (implied by the other tls flags)")
+	fs.StringVar(&f.config.CAFile, "tls-ca", "", "CA used to verify the certificate of the node. Empty uses the system roots")
+	fs.StringVar(&f.config.CertFile, "tls-cert", "", "client certificate (PEM), for nodes that require mutual TLS")
+	fs.StringVar(&f.config.KeyFile, "tls-key", "", "key of the client certificate (PEM)")
+	fs.StringVar(&f.config.ServerName, "tls-server-name", "", "name expected in the certificate of the node. Empty uses the dialed host")
+	return f
+}
+
+func (f *tlsFlags) isSet() bool {
+	c := f.config
+	return f.enabled || c.CAFile != "" || c.CertFile != "" || c.KeyFile != "" || c.ServerName != ""
+}
+
+// Connects to the gRPC API of a node, with TLS when it is set by the flags
+func dial(addr string, tls *tlsFlags) (*grpc.ClientConn, error) {
+	if !tls.isSet() {
+		return grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
-	c := proto.NewNodeClient(client)
-	privKey := crypto.GeneratePrivateKey()
-	tx := &proto.Transaction{
-		Version: 1,
-		Inputs: []*proto.TxInput{
-			{
-				PrevTxHash:   util.RandomHash(),
-				PrevOutIndex: 0,
-				PublicKey:    privKey.Public().Bytes(),
-			},
-		},
-		Outputs: []*proto.TxOutput{
-			{
-				Amount:  99,
-				Address: privKey.Public().Address().Bytes(),
-			},
-		},
+	creds, err := tls.config.ClientCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS config: %w", err)
 	}
+	return grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+}
 
-	_, err = c.HandleTransaction(context.TODO(), tx)
-	if err != nil {
-		log.Fatal(err)
+// Adds the admin token to the metadata of the calls made with the context
+func withAdminToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
 	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
 }
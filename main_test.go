@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunUnknownCommand(t *testing.T) {
+	assert.NotNil(t, run([]string{"chain", "unknown"}))
+	assert.Nil(t, run([]string{"help"}))
+}
+
+func TestPromptsReadPipedInput(t *testing.T) {
+	t.Setenv(passphraseEnv, "")
+	os.Unsetenv(passphraseEnv)
+	stdin = bufio.NewReader(strings.NewReader("word word word\npassphrase\n"))
+	t.Cleanup(func() { stdin = bufio.NewReader(os.Stdin) })
+
+	// each prompt reads its own line of the input
+	mnemonic, err := readLine("mnemonic")
+	require.Nil(t, err)
+	assert.Equal(t, "word word word", mnemonic)
+	passphrase, err := readPassphrase("", "passphrase")
+	require.Nil(t, err)
+	assert.Equal(t, "passphrase", passphrase)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/CaiqueRibeiro/blocker/config"
+	"github.com/CaiqueRibeiro/blocker/node"
+)
+
+/*
+Runs a node until it is interrupted.
+
+The config is loaded from the file in --config and from the BLOCKER_* environment variables,
+and the flags informed in the command line override both
+*/
+func runNode(args []string) error {
+	var (
+		fs           = newFlagSet("node run")
+		configPath   = fs.String("config", "", "config file (YAML, TOML or JSON)")
+		listen       = fs.String("listen", ":3000", "address of the node service")
+		bootstrap    = fs.String("bootstrap", "", "comma separated addresses of the nodes to connect on startup")
+		validatorKey = fs.String("validator-key", "", "key file (plain or encrypted) of the validator. Empty runs a node that does not create blocks")
+		passFile     = fs.String("passphrase-file", "", "file with the passphrase of an encrypted validator key")
+		genesis      = fs.String("genesis", "", "genesis file of the chain. Empty uses the development genesis")
+		dataDir      = fs.String("datadir", "", "directory of the node data. Empty keeps everything in memory")
+		httpListen   = fs.String("http", "", "address of the HTTP gateway. Empty does not start the gateway")
+		adminListen  = fs.String("admin-listen", "", "address of the admin service. Empty serves it in the node address")
+		adminToken   = fs.String("admin-token", "", "token required by the admin service")
+		allowedPeers = fs.String("allowed-peers", "", "comma separated node IDs allowed to connect with the node. Empty allows any node")
+		tlsCert      = fs.String("tls-cert", "", "certificate of the node (PEM). Empty uses insecure connections")
+		tlsKey       = fs.String("tls-key", "", "key of the certificate of the node (PEM)")
+		tlsCA        = fs.String("tls-ca", "", "CA used to verify the certificates of other nodes. Empty uses the system roots")
+		tlsMutual    = fs.Bool("tls-require-client-cert", false, "requires and verifies the certificate of the nodes that connect")
+		tlsName      = fs.String("tls-server-name", "", "name expected in the certificate of dialed nodes. Empty uses the dialed host")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Listen = *listen
+		case "bootstrap":
+			cfg.Bootstrap = strings.Split(*bootstrap, ",")
+		case "validator-key":
+			cfg.ValidatorKey = *validatorKey
+		case "passphrase-file":
+			cfg.PassphraseFile = *passFile
+		case "genesis":
+			cfg.Genesis = *genesis
+		case "datadir":
+			cfg.DataDir = *dataDir
+		case "http":
+			cfg.HTTPListen = *httpListen
+		case "admin-listen":
+			cfg.AdminListen = *adminListen
+		case "admin-token":
+			cfg.AdminToken = *adminToken
+		case "allowed-peers":
+			cfg.AllowedPeers = strings.Split(*allowedPeers, ",")
+		case "tls-cert":
+			cfg.TLS.Cert = *tlsCert
+		case "tls-key":
+			cfg.TLS.Key = *tlsKey
+		case "tls-ca":
+			cfg.TLS.CA = *tlsCA
+		case "tls-require-client-cert":
+			cfg.TLS.RequireClientCert = *tlsMutual
+		case "tls-server-name":
+			cfg.TLS.ServerName = *tlsName
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	serverConfig, err := cfg.ServerConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return node.NewNode(serverConfig).Start(ctx)
+}
@@ -0,0 +1,214 @@
+package node
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// gRPC service used by the operators of the node
+type AdminServer struct {
+	node *Node
+
+	proto.UnimplementedAdminServer
+}
+
+func NewAdminServer(n *Node) *AdminServer {
+	return &AdminServer{node: n}
+}
+
+func (s *AdminServer) ListBannedPeers(ctx context.Context, req *proto.ListBannedPeersRequest) (*proto.BannedPeerList, error) {
+	keys, bans := s.node.bans.List()
+	list := &proto.BannedPeerList{}
+	for _, key := range keys {
+		list.Peers = append(list.Peers, &proto.BannedPeer{
+			Peer:        key,
+			BannedUntil: bans[key].UnixNano(),
+		})
+	}
+	return list, nil
+}
+
+func (s *AdminServer) UnbanPeer(ctx context.Context, req *proto.UnbanPeerRequest) (*proto.Ack, error) {
+	ok, err := s.node.bans.Unban(req.Peer)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("peer %s is not banned", req.Peer)
+	}
+	s.node.scores.Reset(req.Peer)
+	s.node.logger.Infow("peer unbanned", "peer", req.Peer)
+	return &proto.Ack{}, nil
+}
+
+func (s *AdminServer) GetMetrics(ctx context.Context, req *proto.GetMetricsRequest) (*proto.Metrics, error) {
+	return &proto.Metrics{Counters: s.node.metrics.Snapshot()}, nil
+}
+
+// Lists the connected peers, sorted by ID
+func (s *AdminServer) ListPeers(ctx context.Context, req *proto.ListPeersRequest) (*proto.PeerList, error) {
+	s.node.peerLock.RLock()
+	defer s.node.peerLock.RUnlock()
+	list := &proto.PeerList{}
+	for id, peer := range s.node.peers {
+		list.Peers = append(list.Peers, peerInfo(id, peer))
+	}
+	sort.Slice(list.Peers, func(i, j int) bool {
+		return list.Peers[i].Id < list.Peers[j].Id
+	})
+	return list, nil
+}
+
+// Dials the node in the address and adds it to the connected peers
+func (s *AdminServer) ConnectPeer(ctx context.Context, req *proto.ConnectPeerRequest) (*proto.PeerInfo, error) {
+	if !s.node.canConnectWith(req.Address) {
+		return nil, status.Errorf(codes.AlreadyExists, "already connected with %s", req.Address)
+	}
+	peer, err := s.node.dialRemoteWork(ctx, req.Address)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	id := NodeIDFromPublicKey(peer.version.PublicKey)
+	if !s.node.addPeer(id, peer) {
+		return nil, status.Errorf(codes.FailedPrecondition, "node %s was not added to the peers", id)
+	}
+	s.node.logger.Infow("peer connected by admin", "id", id, "address", req.Address)
+	return peerInfo(id, peer), nil
+}
+
+func (s *AdminServer) DisconnectPeer(ctx context.Context, req *proto.DisconnectPeerRequest) (*proto.Ack, error) {
+	s.node.peerLock.RLock()
+	_, ok := s.node.peers[req.Id]
+	s.node.peerLock.RUnlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "peer %s is not connected", req.Id)
+	}
+	s.node.deletePeer(req.Id)
+	s.node.logger.Infow("peer disconnected by admin", "id", req.Id)
+	return &proto.Ack{}, nil
+}
+
+func (s *AdminServer) GetMempool(ctx context.Context, req *proto.GetMempoolRequest) (*proto.MempoolInfo, error) {
+	txx := s.node.mempool.Transactions()
+	info := &proto.MempoolInfo{Size: uint32(len(txx))}
+	for _, tx := range txx {
+		info.Bytes += uint64(pb.Size(tx))
+	}
+	if req.IncludeTransactions {
+		info.Transactions = txx
+	}
+	return info, nil
+}
+
+func (s *AdminServer) GetChainTip(ctx context.Context, req *proto.GetChainTipRequest) (*proto.ChainTip, error) {
+	height := s.node.chain.Height()
+	block, err := s.node.chain.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ChainTip{
+		Height: int32(height),
+		Hash:   types.HashBlock(block),
+		Header: block.Header,
+	}, nil
+}
+
+// Changes the level of the node logger, returning the level in use
+func (s *AdminServer) SetLogLevel(ctx context.Context, req *proto.SetLogLevelRequest) (*proto.LogLevel, error) {
+	if req.Level != "" {
+		if err := s.node.logLevel.UnmarshalText([]byte(req.Level)); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		s.node.logger.Infow("log level changed", "level", req.Level)
+	}
+	return &proto.LogLevel{Level: s.node.logLevel.String()}, nil
+}
+
+// Stops the creation of blocks. Transactions are still accepted, waiting in the mempool
+func (s *AdminServer) PauseValidator(ctx context.Context, req *proto.PauseValidatorRequest) (*proto.ValidatorStatus, error) {
+	if s.node.PrivateKey == nil {
+		return nil, status.Error(codes.FailedPrecondition, "node is not a validator")
+	}
+	s.node.paused.Store(true)
+	s.node.logger.Infow("validator paused")
+	return s.validatorStatus(), nil
+}
+
+func (s *AdminServer) ResumeValidator(ctx context.Context, req *proto.ResumeValidatorRequest) (*proto.ValidatorStatus, error) {
+	if s.node.PrivateKey == nil {
+		return nil, status.Error(codes.FailedPrecondition, "node is not a validator")
+	}
+	s.node.paused.Store(false)
+	s.node.logger.Infow("validator resumed")
+	return s.validatorStatus(), nil
+}
+
+func (s *AdminServer) validatorStatus() *proto.ValidatorStatus {
+	return &proto.ValidatorStatus{
+		Validator: s.node.PrivateKey != nil,
+		Paused:    s.node.paused.Load(),
+	}
+}
+
+// Must be called with the peer lock held
+func peerInfo(id string, peer *remotePeer) *proto.PeerInfo {
+	return &proto.PeerInfo{
+		Id:         id,
+		ListenAddr: peer.version.ListenAddr,
+		Version:    peer.version.Version,
+		Height:     peer.height,
+		PublicKey:  peer.version.PublicKey,
+	}
+}
+
+/*
+Requires the admin token in the calls to the admin service, when the node has one.
+Without a token, only the calls from the loopback interface are accepted, so a node whose admin service
+is served in its public address cannot be controlled by its peers
+*/
+func (n *Node) adminAuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/Admin/") {
+		return handler(ctx, req)
+	}
+	if n.AdminToken == "" {
+		if !fromLoopback(ctx) {
+			return nil, status.Error(codes.PermissionDenied, "the admin service without a token only accepts local calls")
+		}
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(n.AdminToken)) == 1 {
+			return handler(ctx, req)
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid admin token")
+}
+
+// Verifies if the call was made from the loopback interface
+func fromLoopback(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
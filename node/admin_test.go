@@ -0,0 +1,162 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminPeers(t *testing.T) {
+	var (
+		n, _       = startTestNode(t, ServerConfig{})
+		remote, ra = startTestNode(t, ServerConfig{})
+		admin      = NewAdminServer(n)
+		ctx        = context.Background()
+	)
+	info, err := admin.ConnectPeer(ctx, &proto.ConnectPeerRequest{Address: ra})
+	require.Nil(t, err)
+	assert.Equal(t, remote.ID(), info.Id)
+	assert.Equal(t, ra, info.ListenAddr)
+
+	_, err = admin.ConnectPeer(ctx, &proto.ConnectPeerRequest{Address: ra})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	list, err := admin.ListPeers(ctx, &proto.ListPeersRequest{})
+	require.Nil(t, err)
+	require.Len(t, list.Peers, 1)
+	assert.Equal(t, remote.ID(), list.Peers[0].Id)
+	assert.Equal(t, "blocker-0.1", list.Peers[0].Version)
+	assert.Equal(t, int32(0), list.Peers[0].Height)
+
+	_, err = admin.DisconnectPeer(ctx, &proto.DisconnectPeerRequest{Id: remote.ID()})
+	require.Nil(t, err)
+	list, err = admin.ListPeers(ctx, &proto.ListPeersRequest{})
+	require.Nil(t, err)
+	assert.Empty(t, list.Peers)
+
+	_, err = admin.DisconnectPeer(ctx, &proto.DisconnectPeerRequest{Id: remote.ID()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestAdminMempoolAndChainTip(t *testing.T) {
+	var (
+		n     = NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()})
+		admin = NewAdminServer(n)
+		ctx   = context.Background()
+		tx    = genesisTransaction(t, n.chain, crypto.GeneratePrivateKey().Public().Address().Bytes(), 100)
+	)
+	ack, err := n.HandleTransaction(peerContext("10.0.0.1:5000"), tx)
+	require.Nil(t, err)
+	require.True(t, ack.Accepted)
+
+	info, err := admin.GetMempool(ctx, &proto.GetMempoolRequest{})
+	require.Nil(t, err)
+	assert.Equal(t, uint32(1), info.Size)
+	assert.NotZero(t, info.Bytes)
+	assert.Empty(t, info.Transactions)
+
+	info, err = admin.GetMempool(ctx, &proto.GetMempoolRequest{IncludeTransactions: true})
+	require.Nil(t, err)
+	require.Len(t, info.Transactions, 1)
+	assert.Equal(t, types.HashTransaction(tx), types.HashTransaction(info.Transactions[0]))
+
+	block := mineBlock(t, n)
+	tip, err := admin.GetChainTip(ctx, &proto.GetChainTipRequest{})
+	require.Nil(t, err)
+	assert.Equal(t, int32(1), tip.Height)
+	assert.Equal(t, types.HashBlock(block), tip.Hash)
+	assert.Equal(t, block.Header, tip.Header)
+}
+
+func TestAdminLogLevel(t *testing.T) {
+	var (
+		n     = NewNode(ServerConfig{ListenAddr: ":3000"})
+		admin = NewAdminServer(n)
+	)
+	level, err := admin.SetLogLevel(context.Background(), &proto.SetLogLevelRequest{Level: "warn"})
+	require.Nil(t, err)
+	assert.Equal(t, "warn", level.Level)
+	assert.False(t, n.logger.Desugar().Core().Enabled(zap.DebugLevel))
+
+	level, err = admin.SetLogLevel(context.Background(), &proto.SetLogLevelRequest{})
+	require.Nil(t, err)
+	assert.Equal(t, "warn", level.Level)
+
+	_, err = admin.SetLogLevel(context.Background(), &proto.SetLogLevelRequest{Level: "loud"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAdminPauseValidator(t *testing.T) {
+	var (
+		validator = NewAdminServer(NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()}))
+		node      = NewAdminServer(NewNode(ServerConfig{ListenAddr: ":4000"}))
+		ctx       = context.Background()
+	)
+	s, err := validator.PauseValidator(ctx, &proto.PauseValidatorRequest{})
+	require.Nil(t, err)
+	assert.True(t, s.Validator)
+	assert.True(t, s.Paused)
+
+	s, err = validator.ResumeValidator(ctx, &proto.ResumeValidatorRequest{})
+	require.Nil(t, err)
+	assert.False(t, s.Paused)
+
+	_, err = node.PauseValidator(ctx, &proto.PauseValidatorRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestAdminListenAddrAndToken(t *testing.T) {
+	adminAddr := freeAddr(t)
+	_, addr := startTestNode(t, ServerConfig{AdminListenAddr: adminAddr, AdminToken: "secret"})
+
+	dial := func(addr string) proto.AdminClient {
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.Nil(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return proto.NewAdminClient(conn)
+	}
+	admin := dial(adminAddr)
+
+	_, err := admin.GetChainTip(context.Background(), &proto.GetChainTipRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = admin.GetChainTip(ctx, &proto.GetChainTipRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	tip, err := admin.GetChainTip(ctx, &proto.GetChainTipRequest{})
+	require.Nil(t, err)
+	assert.Equal(t, int32(0), tip.Height)
+
+	// the admin service is not served in the address of the node
+	_, err = dial(addr).GetChainTip(ctx, &proto.GetChainTipRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestAdminWithoutTokenOnlyLocal(t *testing.T) {
+	var (
+		n       = NewNode(ServerConfig{ListenAddr: ":3000"})
+		info    = &grpc.UnaryServerInfo{FullMethod: "/Admin/GetChainTip"}
+		handler = func(ctx context.Context, req any) (any, error) { return &proto.ChainTip{}, nil }
+	)
+	_, err := n.adminAuthInterceptor(peerContext("10.0.0.1:5000"), &proto.GetChainTipRequest{}, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = n.adminAuthInterceptor(peerContext("127.0.0.1:5000"), &proto.GetChainTipRequest{}, info, handler)
+	assert.Nil(t, err)
+
+	// the other services are not restricted
+	_, err = n.adminAuthInterceptor(peerContext("10.0.0.1:5000"), nil, &grpc.UnaryServerInfo{FullMethod: "/Query/GetBlockByHeight"}, handler)
+	assert.Nil(t, err)
+}
@@ -3,37 +3,90 @@ package node
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/CaiqueRibeiro/blocker/crypto"
 	"github.com/CaiqueRibeiro/blocker/proto"
 	"github.com/CaiqueRibeiro/blocker/types"
 )
 
-const seed = "33c3e6749d95d5e9611c3f8e6ebcfe10d840226c46c4df18b7026b64be73a13f"
+// seed of the key that signs the genesis block and owns the allocation of the default genesis
+const GenesisSeed = "33c3e6749d95d5e9611c3f8e6ebcfe10d840226c46c4df18b7026b64be73a13f"
+
+const (
+	BlockVersion       int32 = 1  // version of the headers of the blocks created by the node
+	medianTimeBlocks         = 11 // blocks whose median timestamp a new block must be later than
+	maxFutureBlockTime       = 2 * time.Hour
+)
+
+// Header versions accepted by the chain
+var knownBlockVersions = map[int32]bool{BlockVersion: true}
+
+/*
+Wraps the errors of the blocks that break the rules of the chain (header, consensus, commit or transactions),
+unlike the blocks the chain cannot place yet (unknown parent) or whose fork is not accepted
+*/
+var ErrInvalidBlock = errors.New("invalid block")
+
+/*
+Wraps the errors of the blocks stamped ahead of the clock of the node. They depend on the clocks of the nodes
+(the block may be accepted later), so they are not a fault of the peer that sent the block
+*/
+var ErrFutureBlock = errors.New("block from the future")
 
 type HeaderList struct {
+	lock    sync.RWMutex
 	headers []*proto.Header
+	heights map[string]int // heights of the headers by hash
 }
 
 func NewHeaderList() *HeaderList {
 	return &HeaderList{
 		headers: make([]*proto.Header, 0),
+		heights: make(map[string]int),
 	}
 }
 
 func (list *HeaderList) Add(h *proto.Header) {
+	list.lock.Lock()
+	defer list.lock.Unlock()
+	list.heights[hex.EncodeToString(types.HashHeader(h))] = len(list.headers)
 	list.headers = append(list.headers, h)
 }
 
+// Removes the last header (the chain tip)
+func (list *HeaderList) RemoveLast() {
+	list.lock.Lock()
+	defer list.lock.Unlock()
+	last := list.headers[len(list.headers)-1]
+	delete(list.heights, hex.EncodeToString(types.HashHeader(last)))
+	list.headers = list.headers[:len(list.headers)-1]
+}
+
+// Returns the height of the header with the hash, if it is in the list
+func (list *HeaderList) HeightOf(hash []byte) (int, bool) {
+	list.lock.RLock()
+	defer list.lock.RUnlock()
+	height, ok := list.heights[hex.EncodeToString(hash)]
+	return height, ok
+}
+
 func (list *HeaderList) Get(index int) *proto.Header {
-	if index > list.Height() {
+	list.lock.RLock()
+	defer list.lock.RUnlock()
+	if index >= len(list.headers) {
 		panic("index too high")
 	}
 	return list.headers[index]
 }
 
 func (list *HeaderList) Len() int {
+	list.lock.RLock()
+	defer list.lock.RUnlock()
 	return len(list.headers)
 }
 
@@ -45,53 +98,193 @@ type UTXO struct {
 	Hash     string
 	OutIndex int
 	Amount   int64
+	Address  []byte
 	Spent    bool
 }
 
+// Block in which a transaction was added to the chain
+type TxLocation struct {
+	BlockHash []byte
+	Height    int
+}
+
 type Chain struct {
+	lock       sync.Mutex // serializes the blocks being added
 	txStore    TXStorer
 	blockStore BlockStorer
 	utxoStore  UTXOStorer
-	headers    HeaderList
+	headers    *HeaderList
+	indexLock  sync.RWMutex
+	txBlocks   map[string]TxLocation // locations of the transactions by hash
+	events     *EventBus             // receives the blocks added to the chain (optional)
+	genesis    *Genesis
+	consensus  Consensus     // rules to accept the blocks and choose between forks
+	sigCache   *SigCache     // transactions whose signatures were verified
+	finalized  *proto.Commit // certificate of the last finalized block (nil if no block was finalized)
+	validators *validatorHistory
 }
 
+// Creates a chain with the default genesis
 func NewChain(bs BlockStorer, txs TXStorer) *Chain {
+	chain, err := NewChainWithGenesis(bs, txs, DefaultGenesis())
+	if err != nil {
+		panic(err)
+	}
+	return chain
+}
+
+// Creates a chain starting with the genesis block of the specification, with the consensus of the genesis
+func NewChainWithGenesis(bs BlockStorer, txs TXStorer, genesis *Genesis) (*Chain, error) {
+	consensus, err := NewConsensus(genesis)
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis: %w", err)
+	}
+	return NewChainWithConsensus(bs, txs, genesis, consensus)
+}
+
+// Creates a chain starting with the genesis block of the specification, whose blocks follow the rules of the consensus
+func NewChainWithConsensus(bs BlockStorer, txs TXStorer, genesis *Genesis, consensus Consensus) (*Chain, error) {
+	block, err := genesis.Block()
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis: %w", err)
+	}
 	chain := &Chain{
 		txStore:    txs,
 		blockStore: bs,
 		utxoStore:  NewMemoryUTXOStore(),
-		headers:    *NewHeaderList(),
+		headers:    NewHeaderList(),
+		txBlocks:   make(map[string]TxLocation),
+		genesis:    genesis,
+		consensus:  consensus,
+		sigCache:   NewSigCache(DefaultSigCacheSize),
 	}
-	chain.addBlock(createGenesisBlock())
-	return chain
+	var validators *ValidatorSet
+	if poa, ok := consensus.(*ProofOfAuthority); ok {
+		validators = poa.Validators()
+	}
+	var epochLength int
+	if genesis.Staking != nil {
+		epochLength = genesis.Staking.EpochLength
+	}
+	chain.validators = newValidatorHistory(validators, epochLength)
+	if err := chain.addBlock(block); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// Specification of the genesis block and parameters of the chain
+func (c *Chain) Genesis() *Genesis {
+	return c.genesis
+}
+
+func (c *Chain) Consensus() Consensus {
+	return c.consensus
+}
+
+// Height of the last finalized block. Blocks up to this height are never reverted
+func (c *Chain) FinalizedHeight() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.finalizedHeight()
+}
+
+func (c *Chain) finalizedHeight() int {
+	if c.finalized == nil {
+		return 0 // the genesis block
+	}
+	return int(c.finalized.Height)
+}
+
+// Commit certificate of the last finalized block (nil if no block was finalized)
+func (c *Chain) LastCommit() *proto.Commit {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.finalized
+}
+
+// Finalizes the block of the commit and its ancestors, after verifying the commit
+func (c *Chain) Finalize(commit *proto.Commit) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if int(commit.Height) <= c.finalizedHeight() {
+		return nil
+	}
+	if err := c.verifyCommit(commit); err != nil {
+		return err
+	}
+	c.finalized = commit
+	return nil
+}
+
+// Verifies that the commit is signed by a quorum of the validators of its height for a block of the chain
+func (c *Chain) verifyCommit(commit *proto.Commit) error {
+	if c.Validators(0) == nil {
+		return fmt.Errorf("the consensus has no validators to finalize blocks")
+	}
+	if commit.Height < 0 || int(commit.Height) > c.Height() {
+		return fmt.Errorf("commit of height %d, the chain height is %d", commit.Height, c.Height())
+	}
+	if !bytes.Equal(types.HashHeader(c.headers.Get(int(commit.Height))), commit.BlockHash) {
+		return fmt.Errorf("commit of block %s, which is not in the chain", hex.EncodeToString(commit.BlockHash))
+	}
+	return c.Validators(int(commit.Height)).VerifyCommit(commit)
 }
 
 func (c *Chain) Height() int {
 	return c.headers.Height()
 }
 
-func (c *Chain) AddBlock(b *proto.Block) error {
+/*
+Adds the block to the chain. A block that is not on top of the chain tip is added to a fork,
+which replaces the blocks of the chain if the consensus chooses it.
+Returns false if the main chain did not change (the block was stored in a fork that was not chosen)
+*/
+func (c *Chain) AddBlock(b *proto.Block) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if b.Header != nil && !bytes.Equal(b.Header.PrevHash, types.HashHeader(c.headers.Get(c.Height()))) {
+		return c.addForkBlock(b)
+	}
 	if err := c.ValidateBlock(b); err != nil {
-		return err
+		return false, fmt.Errorf("%w: %w", ErrInvalidBlock, err)
 	}
 	if err := c.addBlock(b); err != nil {
-		return err
+		return false, err
 	}
-	return nil
+	c.events.Publish(Event{Block: b})
+	return true, nil
 }
 
 // Add block with validation (to be used outside the chain scope)
 func (c *Chain) addBlock(b *proto.Block) error {
 	c.headers.Add(b.Header)
+	if b.LastCommit != nil && int(b.LastCommit.Height) > c.finalizedHeight() { // verified with the block
+		c.finalized = b.LastCommit
+	}
+	location := TxLocation{
+		BlockHash: types.HashBlock(b),
+		Height:    c.Height(),
+	}
+	validators := c.validators.Latest()
 	for _, tx := range b.Transactions {
 		if err := c.txStore.Put(tx); err != nil {
 			return err
 		}
+		next, err := c.applyValidatorOp(validators, tx)
+		if err != nil {
+			return err
+		}
+		validators = next
 		hash := hex.EncodeToString(types.HashTransaction(tx))
+		c.indexLock.Lock()
+		c.txBlocks[hash] = location
+		c.indexLock.Unlock()
 		for it, output := range tx.Outputs {
 			utxo := &UTXO{
 				Hash:     hash,
 				Amount:   output.Amount,
+				Address:  output.Address,
 				OutIndex: it,
 				Spent:    false,
 			}
@@ -100,8 +293,7 @@ func (c *Chain) addBlock(b *proto.Block) error {
 			}
 		}
 		for _, input := range tx.Inputs {
-			key := fmt.Sprintf("%s_%d", hex.EncodeToString(input.PrevTxHash), input.PrevOutIndex)
-			utxo, err := c.utxoStore.Get(key)
+			utxo, err := c.utxoStore.Get(outputKey(input.PrevTxHash, input.PrevOutIndex))
 			if err != nil {
 				return err
 			}
@@ -111,9 +303,145 @@ func (c *Chain) addBlock(b *proto.Block) error {
 			}
 		}
 	}
+	if validators != c.validators.Latest() {
+		c.validators.add(location.Height, validators)
+	}
 	return c.blockStore.Put(b)
 }
 
+/*
+Adds a block whose parent is not the chain tip:
+ 1. The parent must be known, the block is verified against the consensus rules and stored,
+    unless the fork starts below the finalized height
+ 2. The consensus chooses between the blocks of the chain and the ones of the fork after their common ancestor
+ 3. If the fork is chosen, the blocks of the chain after the ancestor are reverted and the ones of the fork are added,
+    validating their transactions. If a block of the fork is invalid the chain is restored
+*/
+func (c *Chain) addForkBlock(b *proto.Block) (bool, error) {
+	if !types.VerifyBlock(b) {
+		return false, fmt.Errorf("%w: invalid block signature", ErrInvalidBlock)
+	}
+	parent, err := c.GetBlockByHash(b.Header.PrevHash)
+	if err != nil {
+		return false, fmt.Errorf("invalid previous block hash: unknown block %s", hex.EncodeToString(b.Header.PrevHash))
+	}
+	if err := c.validateHeader(parent, b.Header); err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidBlock, err)
+	}
+	if err := c.consensus.VerifyHeader(c, parent.Header, b.Header, b.PublicKey); err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidBlock, err)
+	}
+
+	fork := []*proto.Block{b}
+	ancestor, ok := c.headers.HeightOf(b.Header.PrevHash)
+	for !ok {
+		fork = append([]*proto.Block{parent}, fork...)
+		if parent, err = c.GetBlockByHash(parent.Header.PrevHash); err != nil {
+			return false, err
+		}
+		ancestor, ok = c.headers.HeightOf(types.HashBlock(parent))
+	}
+	if ancestor < c.finalizedHeight() {
+		return false, fmt.Errorf("fork from height %d, below the finalized height %d", ancestor, c.finalizedHeight())
+	}
+	if err := c.blockStore.Put(b); err != nil {
+		return false, err
+	}
+	current := make([]*proto.Header, 0, c.Height()-ancestor)
+	for height := ancestor + 1; height <= c.Height(); height++ {
+		current = append(current, c.headers.Get(height))
+	}
+	candidate := make([]*proto.Header, len(fork))
+	for i, block := range fork {
+		candidate[i] = block.Header
+	}
+	if !c.consensus.ChooseFork(current, candidate) {
+		return false, nil
+	}
+	if err := c.reorg(ancestor, fork); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Replaces the blocks of the chain after the height of the ancestor by the blocks of the fork, publishing the reverted blocks first
+func (c *Chain) reorg(ancestor int, fork []*proto.Block) error {
+	reverted := make([]*proto.Block, 0, c.Height()-ancestor)
+	for c.Height() > ancestor {
+		block, err := c.GetBlockByHeight(c.Height())
+		if err != nil {
+			return err
+		}
+		if err := c.revertBlock(block); err != nil {
+			return err
+		}
+		reverted = append(reverted, block)
+	}
+	for i, block := range fork {
+		err := c.ValidateBlock(block)
+		if err == nil {
+			err = c.addBlock(block)
+		}
+		if err == nil {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if err := c.revertBlock(fork[j]); err != nil {
+				return err
+			}
+		}
+		for j := len(reverted) - 1; j >= 0; j-- {
+			if err := c.addBlock(reverted[j]); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("invalid block %s in the fork: %w", hex.EncodeToString(types.HashBlock(block)), err)
+	}
+	for _, block := range reverted {
+		c.events.Publish(Event{Block: block, Reverted: true})
+	}
+	for _, block := range fork {
+		c.events.Publish(Event{Block: block})
+	}
+	return nil
+}
+
+/*
+Removes the last block of the chain, deleting its transactions and the outputs they created and restoring the ones they spent.
+The transactions are no longer known by the chain, so they can be received again
+*/
+func (c *Chain) revertBlock(b *proto.Block) error {
+	for i := len(b.Transactions) - 1; i >= 0; i-- {
+		tx := b.Transactions[i]
+		txHash := types.HashTransaction(tx)
+		hash := hex.EncodeToString(txHash)
+		for _, input := range tx.Inputs {
+			utxo, err := c.utxoStore.Get(outputKey(input.PrevTxHash, input.PrevOutIndex))
+			if err != nil {
+				return err
+			}
+			utxo.Spent = false
+			if err := c.utxoStore.Put(utxo); err != nil {
+				return err
+			}
+		}
+		for it := range tx.Outputs {
+			if err := c.utxoStore.Delete(outputKey(txHash, uint32(it))); err != nil {
+				return err
+			}
+		}
+		if err := c.txStore.Delete(hash); err != nil {
+			return err
+		}
+		c.indexLock.Lock()
+		delete(c.txBlocks, hash)
+		c.indexLock.Unlock()
+	}
+	c.validators.revert(c.Height())
+	c.headers.RemoveLast()
+	return nil
+}
+
 // Add block without validation (to be used internally, ex: creating genesis block)
 func (c *Chain) GetBlockByHash(b []byte) (*proto.Block, error) {
 	hashHex := hex.EncodeToString(b)
@@ -121,6 +449,9 @@ func (c *Chain) GetBlockByHash(b []byte) (*proto.Block, error) {
 }
 
 func (c *Chain) GetBlockByHeight(height int) (*proto.Block, error) {
+	if height < 0 {
+		return nil, fmt.Errorf("invalid height (%d)", height)
+	}
 	if height > c.Height() {
 		return nil, fmt.Errorf("given height (%d) too high - height (%d)", height, c.Height())
 	}
@@ -129,10 +460,44 @@ func (c *Chain) GetBlockByHeight(height int) (*proto.Block, error) {
 	return c.GetBlockByHash(hash)
 }
 
+func (c *Chain) GetTransaction(hash []byte) (*proto.Transaction, error) {
+	return c.txStore.Get(hex.EncodeToString(hash))
+}
+
+// Returns the block in which the transaction was added to the chain
+func (c *Chain) GetTransactionLocation(hash []byte) (TxLocation, bool) {
+	c.indexLock.RLock()
+	defer c.indexLock.RUnlock()
+	location, ok := c.txBlocks[hex.EncodeToString(hash)]
+	return location, ok
+}
+
+// Returns the unspent outputs owned by the address
+func (c *Chain) GetUTXOs(address []byte) ([]*UTXO, error) {
+	return c.utxoStore.ListByAddress(address)
+}
+
+// Returns the sum of the unspent outputs owned by the address
+func (c *Chain) GetBalance(address []byte) (int64, error) {
+	utxos, err := c.GetUTXOs(address)
+	if err != nil {
+		return 0, err
+	}
+	var balance int64
+	for _, utxo := range utxos {
+		balance += utxo.Amount
+	}
+	return balance, nil
+}
+
 /*
 Validates the incomin block to verify if it should be added to the chain
  1. Validates the signature of the block
- 2. Validates if the previous hash of the block is equal to the hash of the last block in the chain
+ 2. Validates if the previous hash of the block is equal to the hash of the last block in the chain,
+    and the height, timestamp and version of the header
+ 3. Verifies the header against the rules of the consensus, and the commit attached to the block (if any)
+ 4. Verifies the signatures of the transactions in parallel, then the outputs they spend
+    and the changes of the validator set they make, in order
 */
 func (c *Chain) ValidateBlock(b *proto.Block) error {
 	// validates the signature of the block
@@ -149,37 +514,134 @@ func (c *Chain) ValidateBlock(b *proto.Block) error {
 	if !bytes.Equal(hash, b.Header.PrevHash) {
 		return fmt.Errorf("invalid previous block hash")
 	}
+	if err := c.validateHeader(currentBlock, b.Header); err != nil {
+		return err
+	}
+	if err := c.consensus.VerifyHeader(c, currentBlock.Header, b.Header, b.PublicKey); err != nil {
+		return err
+	}
+	if b.LastCommit != nil {
+		if err := c.verifyCommit(b.LastCommit); err != nil {
+			return fmt.Errorf("invalid last commit: %w", err)
+		}
+	}
 
+	// the signatures of all the transactions are verified in parallel (except the ones verified in the mempool)
+	if err := c.VerifySignatures(b.Transactions...); err != nil {
+		return err
+	}
+	var (
+		spent      = make(map[string]bool) // outputs spent by the transactions of the block
+		validators = c.validators.Latest()
+	)
 	for _, tx := range b.Transactions {
-		if err := c.ValidateTransaction(tx); err != nil {
+		if err := c.validateSpends(tx); err != nil {
+			return err
+		}
+		if validators, err = c.applyValidatorOp(validators, tx); err != nil {
 			return err
 		}
+		for _, input := range tx.Inputs {
+			key := outputKey(input.PrevTxHash, input.PrevOutIndex)
+			if spent[key] {
+				return fmt.Errorf("output %s is spent twice in the block", key)
+			}
+			spent[key] = true
+		}
 	}
 
 	return nil
 }
 
+/*
+Verifies the header of a block on top of the parent, whatever the consensus:
+ 1. The height is the height of the parent plus one
+ 2. The timestamp is later than the median timestamp of the last medianTimeBlocks blocks (up to the parent),
+    so it always moves forward even if some validators have late clocks, and at most maxFutureBlockTime ahead of the node clock
+ 3. The version is known
+*/
+func (c *Chain) validateHeader(parent *proto.Block, h *proto.Header) error {
+	if h.Height != parent.Header.Height+1 {
+		return fmt.Errorf("invalid block height %d, the parent height is %d", h.Height, parent.Header.Height)
+	}
+	median, err := c.medianTime(parent)
+	if err != nil {
+		return err
+	}
+	if h.Timestamp <= median {
+		return fmt.Errorf("block timestamp %d is not later than the median of the last blocks (%d)", h.Timestamp, median)
+	}
+	if limit := time.Now().Add(maxFutureBlockTime); h.Timestamp > limit.UnixNano() {
+		return fmt.Errorf("%w: block timestamp %s is too far in the future", ErrFutureBlock, time.Unix(0, h.Timestamp).UTC().Format(time.RFC3339))
+	}
+	if !knownBlockVersions[h.Version] {
+		return fmt.Errorf("unknown block version %d", h.Version)
+	}
+	return nil
+}
+
+// Median timestamp of the block and its ancestors, up to medianTimeBlocks blocks
+func (c *Chain) medianTime(b *proto.Block) (int64, error) {
+	timestamps := make([]int64, 0, medianTimeBlocks)
+	for {
+		timestamps = append(timestamps, b.Header.Timestamp)
+		if len(timestamps) == medianTimeBlocks || b.Header.Height == 0 {
+			break
+		}
+		var err error
+		if b, err = c.GetBlockByHash(b.Header.PrevHash); err != nil {
+			return 0, err
+		}
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2], nil
+}
+
+// Verifies the signatures of the transactions, remembering the valid ones so they are not verified again
+func (c *Chain) VerifySignatures(txs ...*proto.Transaction) error {
+	return VerifySignatures(txs, c.sigCache)
+}
+
 func (c *Chain) ValidateTransaction(tx *proto.Transaction) error {
-	if !types.VerifyTransaction(tx) {
-		return fmt.Errorf("invalid transaction signature")
+	if err := c.VerifySignatures(tx); err != nil {
+		return err
+	}
+	if err := c.validateSpends(tx); err != nil {
+		return err
 	}
+	_, err := c.applyValidatorOp(c.validators.Latest(), tx)
+	return err
+}
+
+// Validates the outputs spent by the transaction (its signatures must have been verified)
+func (c *Chain) validateSpends(tx *proto.Transaction) error {
 	// Check if all the outputs are unspent
 	var (
 		nInputs = len(tx.Inputs)
 		hash    = hex.EncodeToString(types.HashTransaction(tx))
 	)
 	sumInputs := 0
+	spent := make(map[string]bool)
 	for i := 0; i < nInputs; i++ {
-		prevHash := hex.EncodeToString(tx.Inputs[i].PrevTxHash)
-		key := fmt.Sprintf("%s_%d", prevHash, i)
+		key := outputKey(tx.Inputs[i].PrevTxHash, tx.Inputs[i].PrevOutIndex)
+		if spent[key] {
+			return fmt.Errorf("input %d of tx %s spends the same output twice", i, hash)
+		}
+		spent[key] = true
 		utxo, err := c.utxoStore.Get(key)
-		sumInputs += int(utxo.Amount)
 		if err != nil {
 			return err
 		}
 		if utxo.Spent {
 			return fmt.Errorf("input %d of tx %s is already spent", i, hash)
 		}
+		if c.staked(key) {
+			return fmt.Errorf("input %d of tx %s spends the stake of a validator", i, hash)
+		}
+		if !c.canSpend(tx.Inputs[i].PublicKey, utxo.Address) {
+			return fmt.Errorf("input %d of tx %s spends an output of another address", i, hash)
+		}
+		sumInputs += int(utxo.Amount)
 	}
 	sumOutputs := 0
 	for _, output := range tx.Outputs {
@@ -191,28 +653,23 @@ func (c *Chain) ValidateTransaction(tx *proto.Transaction) error {
 	return nil
 }
 
-func createGenesisBlock() *proto.Block {
-	privKey := crypto.NewPrivateKeyFromString(seed) // creates a private key by hand because it's a genesis block
-	block := &proto.Block{
-		Header: &proto.Header{
-			Version: 1,
-		},
-	}
-	types.SignBlock(privKey, block)
-
-	tx := &proto.Transaction{
-		Version: 1,
-		Inputs:  []*proto.TxInput{},
-		Outputs: []*proto.TxOutput{
-			{
-				Amount:  1000,
-				Address: privKey.Public().Address().Bytes(),
-			},
-		},
-	}
+/*
+Verifies if the key of an input owns the address of the output it spends.
+Migration from legacy addresses (the key truncated) to hashed ones: outputs of legacy addresses
+are spent by their keys until the legacyAddressHeight of the genesis (at any height if it is zero).
+The change of those transactions goes to hashed addresses, so the coins move to them as they are spent
+*/
+func (c *Chain) canSpend(pubKey []byte, address []byte) bool {
+	legacyHeight := c.genesis.LegacyAddressHeight
+	allowLegacy := legacyHeight == 0 || c.Height()+1 <= legacyHeight // the transaction goes to the next block
+	return crypto.PublicKeyFromBytes(pubKey).Owns(address, allowLegacy)
+}
 
-	block.Transactions = append(block.Transactions, tx)
-	types.SignBlock(privKey, block)
+// Key of an output in the UTXO store
+func outputKey(txHash []byte, outIndex uint32) string {
+	return utxoKey(hex.EncodeToString(txHash), int(outIndex))
+}
 
-	return block
+func utxoKey(hash string, outIndex int) string {
+	return fmt.Sprintf("%s_%d", hash, outIndex)
 }
@@ -1,24 +1,83 @@
 package node
 
 import (
+	"context"
 	"encoding/hex"
 	"testing"
+	"time"
 
 	"github.com/CaiqueRibeiro/blocker/crypto"
 	"github.com/CaiqueRibeiro/blocker/proto"
 	"github.com/CaiqueRibeiro/blocker/types"
-	"github.com/CaiqueRibeiro/blocker/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func randomBlock(t *testing.T, chain *Chain) *proto.Block {
-	privKey := crypto.GeneratePrivateKey()
-	b := util.RandomBlock()
-	prevBlock, err := chain.GetBlockByHeight(chain.Height())
+// Overrides of the block created by blockOn
+type blockOption func(*blockConfig)
+
+type blockConfig struct {
+	key       *crypto.PrivateKey
+	timestamp int64
+	txx       []*proto.Transaction
+	seal      func(parent, b *proto.Block, key *crypto.PrivateKey) // signs the block when nil
+}
+
+/*
+Block on top of the parent, changed by the options. By default it has no transactions,
+is created one second after the parent and is signed by a new key
+*/
+func blockOn(parent *proto.Block, opts ...blockOption) *proto.Block {
+	cfg := &blockConfig{
+		key:       crypto.GeneratePrivateKey(),
+		timestamp: parent.Header.Timestamp + int64(time.Second),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	b := &proto.Block{
+		Header: &proto.Header{
+			Version:   BlockVersion,
+			Height:    parent.Header.Height + 1,
+			PrevHash:  types.HashBlock(parent),
+			Timestamp: cfg.timestamp,
+		},
+		Transactions: cfg.txx,
+	}
+	if cfg.seal != nil {
+		cfg.seal(parent, b, cfg.key)
+	} else {
+		types.SignBlock(cfg.key, b)
+	}
+	return b
+}
+
+func signedBy(key *crypto.PrivateKey) blockOption {
+	return func(cfg *blockConfig) { cfg.key = key }
+}
+
+func at(timestamp time.Time) blockOption {
+	return func(cfg *blockConfig) { cfg.timestamp = timestamp.UnixNano() }
+}
+
+func withTxs(txx ...*proto.Transaction) blockOption {
+	return func(cfg *blockConfig) { cfg.txx = txx }
+}
+
+// Prepares and seals the block with the consensus of the chain (mines it in a proof-of-work chain)
+func sealedFor(t *testing.T, chain *Chain) blockOption {
+	return func(cfg *blockConfig) {
+		cfg.seal = func(parent, b *proto.Block, key *crypto.PrivateKey) {
+			require.Nil(t, chain.Consensus().Prepare(chain, parent.Header, b.Header, nil))
+			require.Nil(t, chain.Consensus().Seal(context.Background(), b, key))
+		}
+	}
+}
+
+// Last block of the chain
+func tip(t *testing.T, chain *Chain) *proto.Block {
+	b, err := chain.GetBlockByHeight(chain.Height())
 	require.Nil(t, err)
-	b.Header.PrevHash = types.HashBlock(prevBlock)
-	types.SignBlock(privKey, b)
 	return b
 }
 
@@ -33,10 +92,11 @@ func TestAddBlock(t *testing.T) {
 	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
 
 	for i := 0; i < 100; i++ {
-		block := randomBlock(t, chain)
+		block := blockOn(tip(t, chain))
 		blockHash := types.HashBlock(block)
 
-		require.Nil(t, chain.AddBlock(block))
+		_, err := chain.AddBlock(block)
+		require.Nil(t, err)
 		fetchedBlock, err := chain.GetBlockByHash(blockHash)
 		require.Nil(t, err)
 		require.Equal(t, block, fetchedBlock)
@@ -51,8 +111,9 @@ func TestChainHeight(t *testing.T) {
 	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
 
 	for i := 0; i < 100; i++ {
-		b := randomBlock(t, chain)
-		require.Nil(t, chain.AddBlock(b))
+		b := blockOn(tip(t, chain))
+		_, err := chain.AddBlock(b)
+		require.Nil(t, err)
 		require.Equal(t, i+1, chain.Height())
 	}
 }
@@ -60,8 +121,8 @@ func TestChainHeight(t *testing.T) {
 func TestAddBlockWithTxInsufficientFunds(t *testing.T) {
 	var (
 		chain     = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
-		block     = randomBlock(t, chain)
-		privKey   = crypto.NewPrivateKeyFromString(seed)
+		block     = blockOn(tip(t, chain))
+		privKey   = crypto.NewPrivateKeyFromString(GenesisSeed)
 		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
 	)
 	prevTx, err := chain.txStore.Get("72fbff407e9b4c36f1e26522be2b4550b5ef6194b770b19bef34a3be202e3fe8")
@@ -89,14 +150,15 @@ func TestAddBlockWithTxInsufficientFunds(t *testing.T) {
 
 	block.Transactions = append(block.Transactions, tx)
 	types.SignBlock(privKey, block)
-	require.NotNil(t, chain.AddBlock(block))
+	_, err = chain.AddBlock(block)
+	require.NotNil(t, err)
 }
 
 func TestAddBlockWithTx(t *testing.T) {
 	var (
 		chain     = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
-		block     = randomBlock(t, chain)
-		privKey   = crypto.NewPrivateKeyFromString(seed)
+		block     = blockOn(tip(t, chain))
+		privKey   = crypto.NewPrivateKeyFromString(GenesisSeed)
 		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
 	)
 	prevTx, err := chain.txStore.Get("72fbff407e9b4c36f1e26522be2b4550b5ef6194b770b19bef34a3be202e3fe8")
@@ -128,10 +190,156 @@ func TestAddBlockWithTx(t *testing.T) {
 
 	block.Transactions = append(block.Transactions, tx)
 	types.SignBlock(privKey, block)
-	require.Nil(t, chain.AddBlock(block))
+	_, err = chain.AddBlock(block)
+	require.Nil(t, err)
 
 	txHash := hex.EncodeToString(types.HashTransaction(tx))
 	fetchedTx, err := chain.txStore.Get(txHash)
 	assert.Nil(t, err)
 	assert.Equal(t, tx, fetchedTx)
 }
+
+// Run with -race: the outputs spent by the blocks are read by the queries at the same time
+func TestQueryUTXOsDuringAddBlock(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey = crypto.NewPrivateKeyFromString(GenesisSeed)
+		address = privKey.Public().Address().Bytes()
+		stop    = make(chan struct{})
+		done    = make(chan struct{})
+	)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	prevTx := genesis.Transactions[0]
+
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			utxos, err := chain.GetUTXOs(address)
+			assert.Nil(t, err)
+			for _, utxo := range utxos {
+				assert.False(t, utxo.Spent)
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		tx := &proto.Transaction{
+			Version: 1,
+			Inputs:  []*proto.TxInput{{PrevTxHash: types.HashTransaction(prevTx), PublicKey: privKey.Public().Bytes()}},
+			Outputs: []*proto.TxOutput{{Amount: prevTx.Outputs[0].Amount, Address: address}},
+		}
+		tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+		block := blockOn(tip(t, chain))
+		block.Transactions = append(block.Transactions, tx)
+		types.SignBlock(privKey, block)
+		_, err := chain.AddBlock(block)
+		require.Nil(t, err)
+		prevTx = tx
+	}
+	close(stop)
+	<-done
+
+	// the spent outputs left the index of the address
+	utxos, err := chain.GetUTXOs(address)
+	require.Nil(t, err)
+	require.Len(t, utxos, 1)
+	assert.Equal(t, hex.EncodeToString(types.HashTransaction(prevTx)), utxos[0].Hash)
+	assert.Equal(t, int64(1000), utxos[0].Amount)
+}
+
+func TestValidateTransactionOwner(t *testing.T) {
+	var (
+		owner     = crypto.GeneratePrivateKey()
+		legacy    = crypto.GeneratePrivateKey()
+		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		genesis   = testGenesis()
+	)
+	genesis.Allocations = []Allocation{
+		{Address: owner.Public().Address().String(), Amount: 500},
+		{Address: legacy.Public().LegacyAddress().String(), Amount: 700},
+	}
+	genesis.LegacyAddressHeight = 2
+	genesis.Validators = nil // blocks signed by any key
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	spend := func(key *crypto.PrivateKey, outIndex uint32) *proto.Transaction {
+		tx := &proto.Transaction{
+			Version: 1,
+			Inputs: []*proto.TxInput{
+				{
+					PrevTxHash:   types.HashTransaction(genesisBlock.Transactions[0]),
+					PrevOutIndex: outIndex,
+					PublicKey:    key.Public().Bytes(),
+				},
+			},
+			Outputs: []*proto.TxOutput{{Amount: 100, Address: toAddress}},
+		}
+		tx.Inputs[0].Signature = types.SignTransaction(key, tx).Bytes()
+		return tx
+	}
+
+	assert.Nil(t, chain.ValidateTransaction(spend(owner, 0)))
+	assert.ErrorContains(t, chain.ValidateTransaction(spend(legacy, 0)), "another address")
+
+	// the legacy output is spendable by its key until the block of legacyAddressHeight
+	assert.Nil(t, chain.ValidateTransaction(spend(legacy, 1)))
+	_, err = chain.AddBlock(blockOn(tip(t, chain)))
+	require.Nil(t, err)
+	assert.Nil(t, chain.ValidateTransaction(spend(legacy, 1)))
+	_, err = chain.AddBlock(blockOn(tip(t, chain)))
+	require.Nil(t, err)
+	assert.ErrorContains(t, chain.ValidateTransaction(spend(legacy, 1)), "another address")
+}
+
+func TestValidateHeader(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	parent := genesis
+	for i := 0; i < 3; i++ { // timestamps 1s, 2s and 3s after the genesis
+		parent = blockOn(parent)
+		_, err = chain.AddBlock(parent)
+		require.Nil(t, err)
+	}
+	resigned := func(b *proto.Block) *proto.Block {
+		types.SignBlock(crypto.GeneratePrivateKey(), b)
+		return b
+	}
+
+	b := blockOn(parent)
+	b.Header.Height = 5
+	_, err = chain.AddBlock(resigned(b))
+	assert.ErrorContains(t, err, "invalid block height 5, the parent height is 3")
+	b = blockOn(parent)
+	b.Header.Version = 2
+	_, err = chain.AddBlock(resigned(b))
+	assert.ErrorContains(t, err, "unknown block version 2")
+	b = blockOn(parent)
+	b.Header.Timestamp = time.Now().Add(3 * time.Hour).UnixNano()
+	_, err = chain.AddBlock(resigned(b))
+	assert.ErrorContains(t, err, "too far in the future")
+	assert.ErrorIs(t, err, ErrFutureBlock)
+
+	// the timestamp may be earlier than the parent, but not than the median of the last blocks (2s)
+	b = blockOn(parent)
+	b.Header.Timestamp = genesis.Header.Timestamp + 2*int64(time.Second)
+	_, err = chain.AddBlock(resigned(b))
+	assert.ErrorContains(t, err, "not later than the median")
+	b.Header.Timestamp++
+	_, err = chain.AddBlock(resigned(b))
+	require.Nil(t, err)
+
+	// blocks of forks follow the same rules
+	b = blockOn(genesis)
+	b.Header.Height = 2
+	_, err = chain.AddBlock(resigned(b))
+	assert.ErrorContains(t, err, "invalid block height")
+}
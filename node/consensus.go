@@ -0,0 +1,92 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+)
+
+// Blocks known by the chain (in the chain or in its forks), for the engines whose rules depend on the ancestors of a block
+type ChainReader interface {
+	GetBlockByHash(hash []byte) (*proto.Block, error)
+	// Validator set active at the height of the chain (nil if the chain has no validators)
+	Validators(height int) *ValidatorSet
+}
+
+// Returned by Prepare when the key cannot create the next block yet (ex: it is not the leader of the slot)
+var ErrNotReady = errors.New("not ready to create the next block")
+
+/*
+Rules to create and accept the blocks of a chain.
+
+The validator loop of the node asks the engine if it can create the next block (Prepare), adds the pending
+transactions and seals it (Seal). The chain verifies the headers of the received blocks (VerifyHeader)
+and, when there are competing branches, asks the engine which one to follow (ChooseFork)
+*/
+type Consensus interface {
+	// Verifies if the key can create blocks. Nodes with other keys only follow the chain
+	Authorized(pubKey []byte) bool
+	// Time between the attempts of the validator loop to create a block. Zero uses the block time of the node
+	Interval() time.Duration
+	// Fills the header of the next block (height, parent hash and timestamp are set) to be created by the key on top of the parent.
+	// Returns ErrNotReady if the key cannot create it at the timestamp of the header
+	Prepare(chain ChainReader, parent, header *proto.Header, pubKey []byte) error
+	// Seals the block with its transactions, so it can be added to the chain and sent to the peers
+	Seal(ctx context.Context, b *proto.Block, key *crypto.PrivateKey) error
+	// Verifies the header of a block signed by the key on top of the parent (the signature itself is verified by the chain)
+	VerifyHeader(chain ChainReader, parent, header *proto.Header, pubKey []byte) error
+	// Verifies if the candidate branch should replace the current one. Both have the headers after their common ancestor, oldest first
+	ChooseFork(current, candidate []*proto.Header) bool
+}
+
+// Engine of the genesis: proof-of-work or proof-of-authority when it has validators, otherwise blocks signed by any key
+func NewConsensus(g *Genesis) (Consensus, error) {
+	if g.ProofOfWork != nil {
+		return NewProofOfWork(g), nil
+	}
+	validators, err := NewValidatorSet(g)
+	if err != nil {
+		return nil, err
+	}
+	if validators == nil {
+		return AnySigner{}, nil
+	}
+	return &ProofOfAuthority{validators: validators, staking: g.Staking != nil}, nil
+}
+
+// Accepts blocks signed by any key. Every validator creates a block every block time and the longest branch wins
+type AnySigner struct{}
+
+func (AnySigner) Authorized(pubKey []byte) bool {
+	return true
+}
+
+func (AnySigner) Interval() time.Duration {
+	return 0
+}
+
+func (AnySigner) Prepare(chain ChainReader, parent, header *proto.Header, pubKey []byte) error {
+	return nil
+}
+
+func (AnySigner) Seal(ctx context.Context, b *proto.Block, key *crypto.PrivateKey) error {
+	types.SignBlock(key, b)
+	return nil
+}
+
+func (AnySigner) VerifyHeader(chain ChainReader, parent, header *proto.Header, pubKey []byte) error {
+	return nil
+}
+
+func (AnySigner) ChooseFork(current, candidate []*proto.Header) bool {
+	return longestChain(current, candidate)
+}
+
+// Chooses the branch with more blocks, keeping the current one on a tie (the first seen)
+func longestChain(current, candidate []*proto.Header) bool {
+	return len(candidate) > len(current)
+}
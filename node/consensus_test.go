@@ -0,0 +1,127 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainReorg(t *testing.T) {
+	var (
+		chain    = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		first    = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		second   = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		txFirst  = genesisTransaction(t, chain, first, 100)
+		txSecond = genesisTransaction(t, chain, second, 200) // spends the same output
+	)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	b1 := blockOn(genesis, withTxs(txFirst))
+	_, err = chain.AddBlock(b1)
+	require.Nil(t, err)
+
+	// a fork as long as the chain is kept aside
+	f1 := blockOn(genesis)
+	changed, err := chain.AddBlock(f1)
+	require.Nil(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 1, chain.Height())
+	tip, err := chain.GetBlockByHeight(1)
+	require.Nil(t, err)
+	assert.Equal(t, b1, tip)
+
+	// the longer fork replaces the chain, reverting the transactions of its blocks
+	f2 := blockOn(f1, withTxs(txSecond))
+	changed, err = chain.AddBlock(f2)
+	require.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 2, chain.Height())
+	tip, err = chain.GetBlockByHeight(2)
+	require.Nil(t, err)
+	assert.Equal(t, f2, tip)
+	balance, err := chain.GetBalance(first)
+	require.Nil(t, err)
+	assert.Zero(t, balance)
+	balance, err = chain.GetBalance(second)
+	require.Nil(t, err)
+	assert.Equal(t, int64(200), balance)
+	_, ok := chain.GetTransactionLocation(types.HashTransaction(txFirst))
+	assert.False(t, ok)
+	_, err = chain.GetTransaction(types.HashTransaction(txFirst))
+	assert.NotNil(t, err)
+	location, ok := chain.GetTransactionLocation(types.HashTransaction(txSecond))
+	require.True(t, ok)
+	assert.Equal(t, 2, location.Height)
+
+	_, err = chain.AddBlock(blockOn(blockOn(f2)))
+	assert.ErrorContains(t, err, "unknown block")
+}
+
+func TestChainReorgInvalidFork(t *testing.T) {
+	var (
+		chain = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		key   = crypto.GeneratePrivateKey()
+		tx    = genesisTransaction(t, chain, key.Public().Address().Bytes(), 100)
+	)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	b1 := blockOn(genesis, withTxs(tx))
+	_, err = chain.AddBlock(b1)
+	require.Nil(t, err)
+
+	// the fork spends an output that only exists in the chain
+	spend := &proto.Transaction{
+		Version: 1,
+		Inputs:  []*proto.TxInput{{PrevTxHash: types.HashTransaction(tx), PrevOutIndex: 0, PublicKey: key.Public().Bytes()}},
+		Outputs: []*proto.TxOutput{{Amount: 100, Address: key.Public().Address().Bytes()}},
+	}
+	spend.Inputs[0].Signature = types.SignTransaction(key, spend).Bytes()
+	f1 := blockOn(genesis)
+	_, err = chain.AddBlock(f1)
+	require.Nil(t, err)
+	_, err = chain.AddBlock(blockOn(f1, withTxs(spend)))
+	assert.ErrorContains(t, err, "in the fork")
+
+	// the chain is restored
+	assert.Equal(t, 1, chain.Height())
+	tip, err := chain.GetBlockByHeight(1)
+	require.Nil(t, err)
+	assert.Equal(t, b1, tip)
+	balance, err := chain.GetBalance(key.Public().Address().Bytes())
+	require.Nil(t, err)
+	assert.Equal(t, int64(100), balance)
+}
+
+// Consensus that only accepts blocks signed by a key
+type signerConsensus struct {
+	AnySigner
+	pubKey []byte
+}
+
+func (sc signerConsensus) VerifyHeader(chain ChainReader, parent, header *proto.Header, pubKey []byte) error {
+	if !bytes.Equal(pubKey, sc.pubKey) {
+		return fmt.Errorf("unexpected signer %x", pubKey)
+	}
+	return nil
+}
+
+func TestChainWithConsensus(t *testing.T) {
+	key := crypto.GeneratePrivateKey()
+	chain, err := NewChainWithConsensus(NewMemoryBlockStore(), NewMemoryTXStore(), DefaultGenesis(), signerConsensus{pubKey: key.Public().Bytes()})
+	require.Nil(t, err)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	_, err = chain.AddBlock(blockOn(genesis))
+	assert.ErrorContains(t, err, "unexpected signer")
+
+	b := blockOn(genesis)
+	types.SignBlock(key, b)
+	_, err = chain.AddBlock(b)
+	require.Nil(t, err)
+}
@@ -0,0 +1,139 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+)
+
+const subscriberBuffer = 256 // events kept for a subscriber that is not reading them
+
+var (
+	ErrSlowSubscriber = errors.New("subscriber is too slow, events were dropped")
+	ErrBusClosed      = errors.New("event bus is closed")
+)
+
+// Event published when a block is added to or reverted from the chain, or a transaction is added to the mempool
+type Event struct {
+	Block    *proto.Block       // set for a new block in the chain
+	Reverted bool               // the block was removed from the chain by a reorg
+	Tx       *proto.Transaction // set for a new transaction in the mempool
+}
+
+/*
+Delivers the events of the node to its subscribers.
+
+Publishing never blocks: each subscriber has its own buffer, and a subscriber whose buffer
+is full is disconnected, so a slow consumer cannot hold the chain or the mempool
+*/
+type EventBus struct {
+	lock        sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+type Subscription struct {
+	bus    *EventBus
+	filter func(Event) bool
+	events chan Event
+	err    error // reason the bus closed the subscription
+}
+
+// Subscribes to the events accepted by the filter (all the events if the filter is nil)
+func (b *EventBus) Subscribe(filter func(Event) bool) *Subscription {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	sub := &Subscription{
+		bus:    b,
+		filter: filter,
+		events: make(chan Event, subscriberBuffer),
+	}
+	if b.closed {
+		sub.err = ErrBusClosed
+		close(sub.events)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Sends the event to the subscribers. Safe to call on a nil bus
+func (b *EventBus) Publish(ev Event) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for sub := range b.subscribers {
+		if sub.filter != nil && !sub.filter(ev) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			b.drop(sub, ErrSlowSubscriber)
+		}
+	}
+}
+
+// Closes all the subscriptions, used when the node stops
+func (b *EventBus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	for sub := range b.subscribers {
+		b.drop(sub, ErrBusClosed)
+	}
+}
+
+// Removes the subscriber, closing its channel (must be called with the lock held)
+func (b *EventBus) drop(sub *Subscription, err error) {
+	sub.err = err
+	close(sub.events)
+	delete(b.subscribers, sub)
+}
+
+// Channel of the events, closed when the subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Reason the subscription was closed by the bus. Only valid after the events channel is closed
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	if _, ok := s.bus.subscribers[s]; ok {
+		s.bus.drop(s, nil)
+	}
+}
+
+// Verifies if the transaction sends to the address or spends an output of it
+func txHasAddress(tx *proto.Transaction, address []byte) bool {
+	for _, output := range tx.Outputs {
+		if bytes.Equal(output.Address, address) {
+			return true
+		}
+	}
+	for _, input := range tx.Inputs {
+		if len(input.PublicKey) != crypto.PubKeyLen {
+			continue
+		}
+		if crypto.PublicKeyFromBytes(input.PublicKey).Owns(address, true) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestEventBusFilter(t *testing.T) {
+	bus := NewEventBus()
+	blocks := bus.Subscribe(func(ev Event) bool { return ev.Block != nil })
+	all := bus.Subscribe(nil)
+
+	bus.Publish(Event{Tx: signedTransaction()})
+	bus.Publish(Event{Block: &proto.Block{}})
+
+	assert.NotNil(t, (<-blocks.Events()).Block)
+	assert.Len(t, blocks.Events(), 0)
+	assert.NotNil(t, (<-all.Events()).Tx)
+	assert.NotNil(t, (<-all.Events()).Block)
+
+	blocks.Close()
+	_, ok := <-blocks.Events()
+	assert.False(t, ok)
+	assert.Nil(t, blocks.Err())
+}
+
+func TestEventBusSlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	slow := bus.Subscribe(nil)
+	for i := 0; i < subscriberBuffer+1; i++ {
+		bus.Publish(Event{Block: &proto.Block{}})
+	}
+	for range slow.Events() { // the buffered events are still delivered before the channel is closed
+	}
+	assert.ErrorIs(t, slow.Err(), ErrSlowSubscriber)
+
+	// other subscribers keep receiving events
+	sub := bus.Subscribe(nil)
+	bus.Publish(Event{Block: &proto.Block{}})
+	assert.Len(t, sub.Events(), 1)
+}
+
+func TestEventBusClose(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(nil)
+	bus.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.ErrorIs(t, sub.Err(), ErrBusClosed)
+
+	// subscribing to a closed bus returns a closed subscription
+	sub = bus.Subscribe(nil)
+	_, ok = <-sub.Events()
+	assert.False(t, ok)
+}
+
+func TestSubscriptions(t *testing.T) {
+	n, addr := startTestNode(t, ServerConfig{PrivateKey: crypto.GeneratePrivateKey()})
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	var (
+		client    = proto.NewQueryClient(conn)
+		ctx       = context.Background()
+		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		tx        = genesisTransaction(t, n.chain, toAddress, 100)
+	)
+	blocks, err := client.SubscribeBlocks(ctx, &proto.SubscribeBlocksRequest{})
+	require.Nil(t, err)
+	mempool, err := client.SubscribeMempool(ctx, &proto.SubscribeMempoolRequest{})
+	require.Nil(t, err)
+	address, err := client.SubscribeAddress(ctx, &proto.SubscribeAddressRequest{Address: toAddress})
+	require.Nil(t, err)
+	// streams are registered in the bus only when the server handles them
+	require.Eventually(t, func() bool {
+		n.events.lock.Lock()
+		defer n.events.lock.Unlock()
+		return len(n.events.subscribers) == 4 // the streams and the loop of the node restoring reverted transactions
+	}, time.Second, 10*time.Millisecond)
+
+	ack, err := n.HandleTransaction(peerContext("10.0.0.1:5000"), tx)
+	require.Nil(t, err)
+	require.True(t, ack.Accepted)
+	block := mineBlock(t, n)
+
+	pending, err := mempool.Recv()
+	require.Nil(t, err)
+	assert.Equal(t, ack.Hash, types.HashTransaction(pending))
+
+	received, err := blocks.Recv()
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(block), types.HashBlock(received))
+
+	ev, err := address.Recv()
+	require.Nil(t, err)
+	assert.False(t, ev.Confirmed)
+	assert.Equal(t, ack.Hash, types.HashTransaction(ev.Transaction))
+	ev, err = address.Recv()
+	require.Nil(t, err)
+	assert.True(t, ev.Confirmed)
+	assert.Equal(t, int32(1), ev.BlockHeight)
+	assert.Equal(t, types.HashBlock(block), ev.BlockHash)
+
+	// streams end when the node stops
+	n.Stop()
+	_, err = blocks.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
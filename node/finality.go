@@ -0,0 +1,304 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+)
+
+const (
+	roundTimeoutBlocks  = 2 // block times without a commit before the validators move to the next round
+	roundCheckInterval  = 50 * time.Millisecond
+	maxVoteHeightsAhead = 100 // votes for heights further than this from the chain tip are ignored
+)
+
+// Minimum number of validators of a quorum: more than 2/3 of the set
+func (vs *ValidatorSet) Quorum() int {
+	return 2*vs.Len()/3 + 1
+}
+
+// Verifies that the commit has the precommits of a quorum of validators for its block
+func (vs *ValidatorSet) VerifyCommit(c *proto.Commit) error {
+	signers := make(map[string]bool)
+	for _, sig := range c.Signatures {
+		validator := hex.EncodeToString(sig.PublicKey)
+		if !vs.Contains(sig.PublicKey) {
+			return fmt.Errorf("commit signed by %s, which is not a validator", validator)
+		}
+		if signers[validator] {
+			return fmt.Errorf("commit signed twice by %s", validator)
+		}
+		precommit := &proto.Vote{
+			Type:      proto.VoteType_VOTE_TYPE_PRECOMMIT,
+			Height:    c.Height,
+			Round:     c.Round,
+			BlockHash: c.BlockHash,
+			PublicKey: sig.PublicKey,
+			Signature: sig.Signature,
+		}
+		if !types.VerifyVote(precommit) {
+			return fmt.Errorf("invalid precommit signature of %s", validator)
+		}
+		signers[validator] = true
+	}
+	if len(signers) < vs.Quorum() {
+		return fmt.Errorf("commit has %d precommits, %d are required", len(signers), vs.Quorum())
+	}
+	return nil
+}
+
+type voteKey struct {
+	voteType proto.VoteType
+	height   int32
+	round    int32
+}
+
+// Block precommitted by the validator at a height, and the round of the precommit
+type voteLock struct {
+	hash  []byte
+	round int32
+}
+
+/*
+Finality gadget of a proof-of-authority chain, a simplified Tendermint.
+
+The block created by the leader of a height is the proposal. Each validator prevotes for the block at the tip of its
+chain. When more than 2/3 of the validators prevote for a block in a round, they precommit for it and lock on it: in the
+next rounds of the height they prevote for the locked block, unless more than 2/3 prevote for another block in a later round.
+More than 2/3 of precommits for a block in a round make a commit, which finalizes the block and its ancestors.
+A round without commit for roundTimeoutBlocks block times moves to the next round.
+The votes of a height are counted among the validators active at the height.
+
+Blocks keep being created while a height is not finalized, and the leaders attach the last commit to their blocks,
+so the nodes that do not receive the votes finalize them too
+*/
+type Finality struct {
+	lock       sync.Mutex
+	chain      *Chain
+	key        *crypto.PrivateKey // key of the node. It only votes at the heights where it is a validator
+	broadcast  func(any)          // sends the votes of the validator to the peers
+	blockTime  time.Duration
+	height     int32 // height being finalized (the chain tip)
+	round      int32
+	roundStart time.Time
+	votes      map[voteKey]map[string]*proto.Vote // votes by validator
+	sent       map[voteKey]bool                   // votes of the validator
+	locks      map[int32]voteLock                 // blocks precommitted by the validator by height
+}
+
+// Finality of the chain. Without a key, the node only collects the votes of the validators
+func NewFinality(chain *Chain, key *crypto.PrivateKey, broadcast func(any)) *Finality {
+	return &Finality{
+		chain:     chain,
+		key:       key,
+		blockTime: time.Duration(chain.Genesis().BlockTime),
+		broadcast: broadcast,
+		votes:     make(map[voteKey]map[string]*proto.Vote),
+		sent:      make(map[voteKey]bool),
+		locks:     make(map[int32]voteLock),
+	}
+}
+
+/*
+Adds a vote received from a peer. Returns false if the vote is already known or is for a finalized height.
+Votes of keys that are not validators, with invalid signatures or for two blocks in the same round are rejected
+*/
+func (f *Finality) AddVote(v *proto.Vote) (bool, error) {
+	if validators := f.chain.Validators(int(v.Height)); validators == nil || !validators.Contains(v.PublicKey) {
+		return false, fmt.Errorf("vote of %x, which is not a validator", v.PublicKey)
+	}
+	if !types.VerifyVote(v) {
+		return false, fmt.Errorf("invalid vote signature")
+	}
+	if int(v.Height) <= f.chain.FinalizedHeight() || int(v.Height) > f.chain.Height()+maxVoteHeightsAhead {
+		return false, nil
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	key := voteKey{voteType: v.Type, height: v.Height, round: v.Round}
+	if known, ok := f.votes[key][hex.EncodeToString(v.PublicKey)]; ok {
+		if !bytes.Equal(known.BlockHash, v.BlockHash) {
+			return false, fmt.Errorf("validator %x voted for two blocks in round %d of height %d", v.PublicKey, v.Round, v.Height)
+		}
+		return false, nil
+	}
+	f.addVote(v)
+	f.catchUp(v.Height, v.Round)
+	f.process(v.Height, v.Round)
+	return true, nil
+}
+
+/*
+Moves to a later round of the current height when more than 1/3 of the validators (at least one honest) vote in it,
+so validators whose rounds timed out at different times meet in the same round
+*/
+func (f *Finality) catchUp(height, round int32) {
+	if height != f.height || round <= f.round {
+		return
+	}
+	voters := make(map[string]bool)
+	for _, voteType := range []proto.VoteType{proto.VoteType_VOTE_TYPE_PREVOTE, proto.VoteType_VOTE_TYPE_PRECOMMIT} {
+		for validator := range f.votes[voteKey{voteType: voteType, height: height, round: round}] {
+			voters[validator] = true
+		}
+	}
+	validators := f.chain.Validators(int(height))
+	if len(voters) > validators.Len()-validators.Quorum() {
+		f.round, f.roundStart = round, time.Now()
+		f.prevote()
+	}
+}
+
+/*
+Follows the chain tip: a new tip starts the voting of its height, and a round that timed out moves to the next one.
+Then the validator prevotes in the current round (if it has not yet), the votes of the round are processed
+and the precommits of blocks that were not in the chain when they were received are tried again
+*/
+func (f *Finality) Update(now time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var (
+		tip       = int32(f.chain.Height())
+		finalized = int32(f.chain.FinalizedHeight())
+	)
+	f.prune(finalized)
+	if tip <= finalized {
+		return
+	}
+	if tip != f.height {
+		f.height, f.round, f.roundStart = tip, 0, now
+	} else if now.Sub(f.roundStart) >= roundTimeoutBlocks*f.blockTime {
+		f.round++
+		f.roundStart = now
+	}
+	f.prevote()
+	f.process(f.height, f.round)
+	for key := range f.votes {
+		if key.voteType == proto.VoteType_VOTE_TYPE_PRECOMMIT {
+			f.commit(key.height, key.round)
+		}
+	}
+}
+
+// Prevotes in the current round for the locked block of the height, or for the chain tip
+func (f *Finality) prevote() {
+	key := voteKey{voteType: proto.VoteType_VOTE_TYPE_PREVOTE, height: f.height, round: f.round}
+	if !f.isValidator(f.height) || f.sent[key] {
+		return
+	}
+	hash := f.hashAt(f.height)
+	if lock, ok := f.locks[f.height]; ok {
+		hash = lock.hash
+	}
+	if hash == nil {
+		return
+	}
+	f.vote(key, hash)
+}
+
+/*
+Processes the votes of a round:
+ 1. With more than 2/3 of prevotes for a block of the chain, the validator precommits for it and locks on it,
+    unless it is locked on another block precommitted in the same or a later round
+ 2. With more than 2/3 of precommits for a block, the chain finalizes it
+*/
+func (f *Finality) process(height, round int32) {
+	precommit := voteKey{voteType: proto.VoteType_VOTE_TYPE_PRECOMMIT, height: height, round: round}
+	if hash := f.quorumHash(voteKey{voteType: proto.VoteType_VOTE_TYPE_PREVOTE, height: height, round: round}); hash != nil {
+		lock, locked := f.locks[height]
+		if f.isValidator(height) && !f.sent[precommit] && bytes.Equal(hash, f.hashAt(height)) &&
+			(!locked || bytes.Equal(lock.hash, hash) || round > lock.round) {
+			f.locks[height] = voteLock{hash: hash, round: round}
+			f.vote(precommit, hash)
+		}
+	}
+	f.commit(height, round)
+}
+
+/*
+Finalizes the block with the precommits of a quorum in the round, if any. If the node does not have the block yet,
+the precommits are kept and the commit is tried again in the next updates
+*/
+func (f *Finality) commit(height, round int32) {
+	precommit := voteKey{voteType: proto.VoteType_VOTE_TYPE_PRECOMMIT, height: height, round: round}
+	hash := f.quorumHash(precommit)
+	if hash == nil || int(height) <= f.chain.FinalizedHeight() {
+		return
+	}
+	commit := &proto.Commit{Height: height, Round: round, BlockHash: hash}
+	for _, v := range f.votes[precommit] {
+		if bytes.Equal(v.BlockHash, hash) {
+			commit.Signatures = append(commit.Signatures, &proto.CommitSignature{PublicKey: v.PublicKey, Signature: v.Signature})
+		}
+	}
+	f.chain.Finalize(commit) // fails while the block is not in the chain
+}
+
+// Verifies if the key of the node is a validator at the height
+func (f *Finality) isValidator(height int32) bool {
+	return f.key != nil && f.chain.Validators(int(height)).Contains(f.key.Public().Bytes())
+}
+
+// Signs and broadcasts a vote of the validator
+func (f *Finality) vote(key voteKey, hash []byte) {
+	v := &proto.Vote{Type: key.voteType, Height: key.height, Round: key.round, BlockHash: hash}
+	types.SignVote(f.key, v)
+	f.sent[key] = true
+	f.addVote(v)
+	f.broadcast(v)
+}
+
+func (f *Finality) addVote(v *proto.Vote) {
+	key := voteKey{voteType: v.Type, height: v.Height, round: v.Round}
+	if f.votes[key] == nil {
+		f.votes[key] = make(map[string]*proto.Vote)
+	}
+	f.votes[key][hex.EncodeToString(v.PublicKey)] = v
+}
+
+// Block voted by a quorum of validators in the round, if any
+func (f *Finality) quorumHash(key voteKey) []byte {
+	counts := make(map[string]int)
+	for _, v := range f.votes[key] {
+		hash := string(v.BlockHash)
+		counts[hash]++
+		if counts[hash] >= f.chain.Validators(int(key.height)).Quorum() {
+			return v.BlockHash
+		}
+	}
+	return nil
+}
+
+// Hash of the block of the chain at the height (nil if the chain is shorter)
+func (f *Finality) hashAt(height int32) []byte {
+	block, err := f.chain.GetBlockByHeight(int(height))
+	if err != nil {
+		return nil
+	}
+	return types.HashBlock(block)
+}
+
+// Forgets the votes of the finalized heights
+func (f *Finality) prune(finalized int32) {
+	for key := range f.votes {
+		if key.height <= finalized {
+			delete(f.votes, key)
+		}
+	}
+	for key := range f.sent {
+		if key.height <= finalized {
+			delete(f.sent, key)
+		}
+	}
+	for height := range f.locks {
+		if height <= finalized {
+			delete(f.locks, height)
+		}
+	}
+}
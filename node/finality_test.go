@@ -0,0 +1,159 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Commit of the block signed by the keys
+func signedCommit(block *proto.Block, keys ...*crypto.PrivateKey) *proto.Commit {
+	commit := &proto.Commit{Height: block.Header.Height, BlockHash: types.HashBlock(block)}
+	for _, key := range keys {
+		v := &proto.Vote{Type: proto.VoteType_VOTE_TYPE_PRECOMMIT, Height: commit.Height, BlockHash: commit.BlockHash}
+		types.SignVote(key, v)
+		commit.Signatures = append(commit.Signatures, &proto.CommitSignature{PublicKey: v.PublicKey, Signature: v.Signature})
+	}
+	return commit
+}
+
+func TestVerifyCommit(t *testing.T) {
+	keys := []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+	vs, err := NewValidatorSet(testGenesis(withValidators(keys...)))
+	require.Nil(t, err)
+	assert.Equal(t, 3, vs.Quorum())
+	block := blockOn(tip(t, NewChain(NewMemoryBlockStore(), NewMemoryTXStore())))
+
+	assert.Nil(t, vs.VerifyCommit(signedCommit(block, keys[0], keys[2], keys[3])))
+	assert.ErrorContains(t, vs.VerifyCommit(signedCommit(block, keys[0], keys[2])), "3 are required")
+	assert.ErrorContains(t, vs.VerifyCommit(signedCommit(block, keys[0], keys[0], keys[1])), "twice")
+	assert.ErrorContains(t, vs.VerifyCommit(signedCommit(block, keys[0], keys[1], crypto.GeneratePrivateKey())), "not a validator")
+	commit := signedCommit(block, keys[0], keys[1], keys[2])
+	commit.Round = 1
+	assert.ErrorContains(t, vs.VerifyCommit(commit), "invalid precommit signature")
+}
+
+func TestFinalityVotes(t *testing.T) {
+	var (
+		keys    = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		genesis = testGenesis(withValidators(keys...))
+		start   = genesis.Timestamp
+		queue   []*proto.Vote // votes broadcasted by the validators
+		gadgets []*Finality
+	)
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	block := blockOn(tip(t, chain), signedBy(keys[1]), at(start.Add(time.Second))) // leader of height 1
+	for _, key := range keys {
+		chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+		require.Nil(t, err)
+		_, err = chain.AddBlock(block)
+		require.Nil(t, err)
+		f := NewFinality(chain, key, func(msg any) {
+			queue = append(queue, msg.(*proto.Vote))
+		})
+		gadgets = append(gadgets, f)
+	}
+
+	// the validators prevote, precommit after the prevotes of all of them (the quorum of 3) and finalize the block
+	for _, f := range gadgets {
+		f.Update(start.Add(time.Second))
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, f := range gadgets {
+			_, err := f.AddVote(v)
+			require.Nil(t, err)
+		}
+	}
+	for _, f := range gadgets {
+		assert.Equal(t, 1, f.chain.FinalizedHeight())
+		assert.Equal(t, types.HashBlock(block), f.chain.LastCommit().BlockHash)
+	}
+
+	// votes for two blocks in the same round are rejected
+	v := &proto.Vote{Type: proto.VoteType_VOTE_TYPE_PREVOTE, Height: 2, BlockHash: types.HashBlock(block)}
+	types.SignVote(keys[0], v)
+	_, err = gadgets[1].AddVote(v)
+	require.Nil(t, err)
+	v = &proto.Vote{Type: proto.VoteType_VOTE_TYPE_PREVOTE, Height: 2, BlockHash: util.RandomHash()}
+	types.SignVote(keys[0], v)
+	_, err = gadgets[1].AddVote(v)
+	assert.ErrorContains(t, err, "two blocks")
+	types.SignVote(crypto.GeneratePrivateKey(), v)
+	_, err = gadgets[1].AddVote(v)
+	assert.ErrorContains(t, err, "not a validator")
+}
+
+func TestChainFinality(t *testing.T) {
+	var (
+		keys    = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		genesis = testGenesis(withValidators(keys...))
+		start   = genesis.Timestamp
+	)
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	b1 := blockOn(tip(t, chain), signedBy(keys[1]), at(start.Add(time.Second)))
+	_, err = chain.AddBlock(b1)
+	require.Nil(t, err)
+	assert.ErrorContains(t, chain.Finalize(signedCommit(b1, keys[0])), "2 are required")
+	assert.Equal(t, 0, chain.FinalizedHeight())
+	commit := signedCommit(b1, keys[0], keys[1])
+	require.Nil(t, chain.Finalize(commit))
+	assert.Equal(t, 1, chain.FinalizedHeight())
+
+	// forks from below the finalized height are rejected
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	fork := &proto.Block{Header: &proto.Header{
+		Version:   1,
+		Height:    1,
+		PrevHash:  types.HashBlock(genesisBlock),
+		Timestamp: start.Add(2 * time.Second).UnixNano(), // round 1, led by the first validator
+	}}
+	types.SignBlock(keys[0], fork)
+	_, err = chain.AddBlock(fork)
+	assert.ErrorContains(t, err, "below the finalized height")
+
+	// nodes that do not receive the votes finalize the blocks with the commits attached to the next blocks
+	b2 := blockOn(tip(t, chain), signedBy(keys[0]), at(start.Add(2*time.Second)))
+	b2.LastCommit = commit
+	_, err = chain.AddBlock(b2)
+	require.Nil(t, err)
+	follower, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	_, err = follower.AddBlock(b1)
+	require.Nil(t, err)
+	b2.LastCommit = signedCommit(b1, keys[0])
+	_, err = follower.AddBlock(b2)
+	assert.ErrorContains(t, err, "invalid last commit")
+	b2.LastCommit = commit
+	_, err = follower.AddBlock(b2)
+	require.Nil(t, err)
+	assert.Equal(t, 1, follower.FinalizedHeight())
+}
+
+func TestFinalityNodes(t *testing.T) {
+	var (
+		keys    = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		genesis = testGenesis(withValidators(keys...), withBlockTime(100*time.Millisecond))
+	)
+	genesis.Timestamp = time.Now().UTC() // the nodes start together, they do not sync past blocks
+	a, addrA := startTestNode(t, ServerConfig{PrivateKey: keys[0], Genesis: genesis})
+	b, _ := startTestNode(t, ServerConfig{PrivateKey: keys[1], Genesis: genesis, BootstrapNodes: []string{addrA}})
+	c, _ := startTestNode(t, ServerConfig{PrivateKey: keys[2], Genesis: genesis, BootstrapNodes: []string{addrA}})
+
+	require.Eventually(t, func() bool {
+		return a.chain.FinalizedHeight() >= 3 && b.chain.FinalizedHeight() >= 3 && c.chain.FinalizedHeight() >= 3
+	}, 10*time.Second, 10*time.Millisecond)
+	block, err := c.chain.GetBlockByHeight(c.chain.Height())
+	require.Nil(t, err)
+	assert.NotNil(t, block.LastCommit)
+}
@@ -0,0 +1,247 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	pb "google.golang.org/protobuf/proto"
+)
+
+/*
+HTTP gateway to the node and query services, for clients that cannot use gRPC.
+
+Messages are encoded with MarshalJSON (protojson with hex encoded bytes), and hashes and addresses
+in the paths are hex encoded:
+  - POST /v1/transactions: submits a transaction, returning its ack
+  - GET /v1/transactions/{hash}: confirmed transaction
+  - GET /v1/transactions/{hash}/status: status of the transaction
+  - GET /v1/blocks/height/{height}: block by height
+  - GET /v1/blocks/hash/{hash}: block by hash
+  - GET /v1/addresses/{address}/balance: balance of the address
+  - GET /v1/addresses/{address}/utxos: unspent outputs of the address
+
+Submitted transactions go through the same ban and rate limit checks as the node service, keyed by the client host
+*/
+type Gateway struct {
+	node  *Node
+	query *QueryServer
+	mux   *http.ServeMux
+}
+
+func NewGateway(n *Node) *Gateway {
+	g := &Gateway{
+		node:  n,
+		query: NewQueryServer(n.chain, n.mempool, n.rejected, n.events),
+		mux:   http.NewServeMux(),
+	}
+	g.mux.HandleFunc("/v1/transactions", g.handleSubmitTransaction)
+	g.mux.HandleFunc("/v1/transactions/", g.handleTransactions)
+	g.mux.HandleFunc("/v1/blocks/", g.handleBlocks)
+	g.mux.HandleFunc("/v1/addresses/", g.handleAddresses)
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) handleSubmitTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(g.node.MaxMsgSize)))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	tx := &proto.Transaction{}
+	if err := UnmarshalJSON(b, tx); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ack, err := g.node.submitTransaction(clientContext(r), tx)
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	code := http.StatusAccepted
+	if !ack.Accepted {
+		code = http.StatusUnprocessableEntity
+	}
+	writeMessage(w, code, ack)
+}
+
+func (g *Gateway) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	parts, ok := pathParts(w, r, "/v1/transactions/")
+	if !ok {
+		return
+	}
+	if len(parts) < 1 || len(parts) > 2 || (len(parts) == 2 && parts[1] != "status") {
+		http.NotFound(w, r)
+		return
+	}
+	hash, err := hex.DecodeString(parts[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid transaction hash %q", parts[0]))
+		return
+	}
+	if len(parts) == 2 {
+		g.respond(w)(g.query.GetTransactionStatus(r.Context(), &proto.GetTransactionStatusRequest{Hash: hash}))
+		return
+	}
+	g.respond(w)(g.query.GetTransaction(r.Context(), &proto.GetTransactionRequest{Hash: hash}))
+}
+
+func (g *Gateway) handleBlocks(w http.ResponseWriter, r *http.Request) {
+	parts, ok := pathParts(w, r, "/v1/blocks/")
+	if !ok {
+		return
+	}
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	switch parts[0] {
+	case "height":
+		height, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid block height %q", parts[1]))
+			return
+		}
+		g.respond(w)(g.query.GetBlockByHeight(r.Context(), &proto.GetBlockByHeightRequest{Height: int32(height)}))
+	case "hash":
+		hash, err := hex.DecodeString(parts[1])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid block hash %q", parts[1]))
+			return
+		}
+		g.respond(w)(g.query.GetBlockByHash(r.Context(), &proto.GetBlockByHashRequest{Hash: hash}))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *Gateway) handleAddresses(w http.ResponseWriter, r *http.Request) {
+	parts, ok := pathParts(w, r, "/v1/addresses/")
+	if !ok {
+		return
+	}
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	address, err := crypto.ParseAddress(parts[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	switch parts[1] {
+	case "balance":
+		g.respond(w)(g.query.GetBalance(r.Context(), &proto.GetBalanceRequest{Address: address.Bytes()}))
+	case "utxos":
+		g.respond(w)(g.query.ListUTXOs(r.Context(), &proto.ListUTXOsRequest{Address: address.Bytes()}))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Writes the response of a query call
+func (g *Gateway) respond(w http.ResponseWriter) func(pb.Message, error) {
+	return func(m pb.Message, err error) {
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		writeMessage(w, http.StatusOK, m)
+	}
+}
+
+// Splits the path after the prefix, accepting only GET requests
+func pathParts(w http.ResponseWriter, r *http.Request, prefix string) ([]string, bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return nil, false
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if path == "" {
+		return nil, true
+	}
+	return strings.Split(path, "/"), true
+}
+
+/*
+Context of a call received by the gateway, carrying the address of the client like the calls received by the gRPC server,
+so the client is identified by its host in the ban list, in the rate limiter and in the peer scores
+*/
+func clientContext(r *http.Request) context.Context {
+	addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+	if err != nil {
+		return r.Context()
+	}
+	return peer.NewContext(r.Context(), &peer.Peer{Addr: addr})
+}
+
+// Handles the transaction with the same checks applied to the calls to the node service
+func (n *Node) submitTransaction(ctx context.Context, tx *proto.Transaction) (*proto.Ack, error) {
+	info := &grpc.UnaryServerInfo{Server: n, FullMethod: "/Node/HandleTransaction"}
+	handle := func(ctx context.Context, req any) (any, error) {
+		return n.HandleTransaction(ctx, req.(*proto.Transaction))
+	}
+	resp, err := n.banInterceptor(ctx, tx, info, func(ctx context.Context, req any) (any, error) {
+		return n.rateLimitInterceptor(ctx, req, info, handle)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*proto.Ack), nil
+}
+
+func writeMessage(w http.ResponseWriter, code int, m pb.Message) {
+	b, err := MarshalJSON(m)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// Writes the error of a gRPC call with the HTTP status equivalent to its code
+func writeStatusError(w http.ResponseWriter, err error) {
+	s := status.Convert(err)
+	code := http.StatusInternalServerError
+	switch s.Code() {
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.InvalidArgument:
+		code = http.StatusBadRequest
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	case codes.ResourceExhausted:
+		code = http.StatusTooManyRequests
+	case codes.Unavailable:
+		code = http.StatusServiceUnavailable
+	}
+	writeError(w, code, fmt.Errorf("%s", s.Message()))
+}
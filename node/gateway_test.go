@@ -0,0 +1,153 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Makes a request to the gateway, decoding the response into m when it is not nil
+func gatewayCall(t *testing.T, server *httptest.Server, method, path string, body []byte, m pb.Message) int {
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(body))
+	require.Nil(t, err)
+	resp, err := server.Client().Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	if m != nil {
+		require.Nil(t, UnmarshalJSON(b, m), string(b))
+	}
+	return resp.StatusCode
+}
+
+func TestJSONHexEncoding(t *testing.T) {
+	tx := signedTransaction()
+	b, err := MarshalJSON(tx)
+	require.Nil(t, err)
+
+	obj := map[string]any{}
+	require.Nil(t, json.Unmarshal(b, &obj))
+	input := obj["inputs"].([]any)[0].(map[string]any)
+	assert.Equal(t, hex.EncodeToString(tx.Inputs[0].PrevTxHash), input["prevTxHash"])
+	output := obj["outputs"].([]any)[0].(map[string]any)
+	assert.Equal(t, crypto.AddressFromBytes(tx.Outputs[0].Address).String(), output["address"])
+
+	decoded := &proto.Transaction{}
+	require.Nil(t, UnmarshalJSON(b, decoded))
+	assert.True(t, pb.Equal(tx, decoded))
+
+	assert.NotNil(t, UnmarshalJSON([]byte(`{"inputs": [{"prevTxHash": "not hex"}]}`), &proto.Transaction{}))
+	// addresses must be bech32 encoded: a hex address (or a typo) is rejected
+	hexAddress := fmt.Sprintf(`{"outputs": [{"address": "%x"}]}`, tx.Outputs[0].Address)
+	assert.NotNil(t, UnmarshalJSON([]byte(hexAddress), &proto.Transaction{}))
+
+	// address fields of other lengths are hex encoded, and decoded back the same way
+	tx.Outputs[0].Address = tx.Outputs[0].Address[:10]
+	b, err = MarshalJSON(tx)
+	require.Nil(t, err)
+	decoded = &proto.Transaction{}
+	require.Nil(t, UnmarshalJSON(b, decoded))
+	assert.True(t, pb.Equal(tx, decoded))
+}
+
+func TestGateway(t *testing.T) {
+	var (
+		n         = NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()})
+		server    = httptest.NewServer(NewGateway(n))
+		to        = crypto.GeneratePrivateKey().Public().Address()
+		toAddress = to.Bytes()
+		tx        = genesisTransaction(t, n.chain, toAddress, 100)
+		hash      = hex.EncodeToString(types.HashTransaction(tx))
+	)
+	defer server.Close()
+
+	body, err := MarshalJSON(tx)
+	require.Nil(t, err)
+	ack := &proto.Ack{}
+	assert.Equal(t, http.StatusAccepted, gatewayCall(t, server, http.MethodPost, "/v1/transactions", body, ack))
+	assert.True(t, ack.Accepted)
+	assert.Equal(t, types.HashTransaction(tx), ack.Hash)
+
+	status := &proto.TransactionStatus{}
+	assert.Equal(t, http.StatusOK, gatewayCall(t, server, http.MethodGet, "/v1/transactions/"+hash+"/status", nil, status))
+	assert.Equal(t, proto.TxStatus_TX_STATUS_PENDING, status.Status)
+	assert.Equal(t, http.StatusNotFound, gatewayCall(t, server, http.MethodGet, "/v1/transactions/"+hash, nil, nil))
+
+	block := mineBlock(t, n)
+	confirmed := &proto.Transaction{}
+	assert.Equal(t, http.StatusOK, gatewayCall(t, server, http.MethodGet, "/v1/transactions/"+hash, nil, confirmed))
+	assert.True(t, pb.Equal(tx, confirmed))
+
+	byHeight := &proto.Block{}
+	assert.Equal(t, http.StatusOK, gatewayCall(t, server, http.MethodGet, "/v1/blocks/height/1", nil, byHeight))
+	assert.True(t, pb.Equal(block, byHeight))
+	byHash := &proto.Block{}
+	path := fmt.Sprintf("/v1/blocks/hash/%x", types.HashBlock(block))
+	assert.Equal(t, http.StatusOK, gatewayCall(t, server, http.MethodGet, path, nil, byHash))
+	assert.True(t, pb.Equal(block, byHash))
+
+	balance := &proto.Balance{}
+	path = fmt.Sprintf("/v1/addresses/%s/balance", to)
+	assert.Equal(t, http.StatusOK, gatewayCall(t, server, http.MethodGet, path, nil, balance))
+	assert.Equal(t, int64(100), balance.Amount)
+	utxos := &proto.UTXOList{}
+	path = fmt.Sprintf("/v1/addresses/%s/utxos", to)
+	assert.Equal(t, http.StatusOK, gatewayCall(t, server, http.MethodGet, path, nil, utxos))
+	require.Len(t, utxos.Utxos, 1)
+	assert.Equal(t, types.HashTransaction(tx), utxos.Utxos[0].TxHash)
+
+	// spending the same output again is rejected
+	body, err = MarshalJSON(genesisTransaction(t, n.chain, toAddress, 200))
+	require.Nil(t, err)
+	ack = &proto.Ack{}
+	assert.Equal(t, http.StatusUnprocessableEntity, gatewayCall(t, server, http.MethodPost, "/v1/transactions", body, ack))
+	assert.False(t, ack.Accepted)
+	assert.NotEmpty(t, ack.Error)
+}
+
+func TestGatewayErrors(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		server = httptest.NewServer(NewGateway(n))
+	)
+	defer server.Close()
+
+	assert.Equal(t, http.StatusBadRequest, gatewayCall(t, server, http.MethodPost, "/v1/transactions", []byte("{"), nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, gatewayCall(t, server, http.MethodGet, "/v1/transactions", nil, nil))
+	assert.Equal(t, http.StatusBadRequest, gatewayCall(t, server, http.MethodGet, "/v1/transactions/xyz", nil, nil))
+	assert.Equal(t, http.StatusBadRequest, gatewayCall(t, server, http.MethodGet, "/v1/blocks/height/abc", nil, nil))
+	assert.Equal(t, http.StatusNotFound, gatewayCall(t, server, http.MethodGet, "/v1/blocks/height/5", nil, nil))
+	address := crypto.GeneratePrivateKey().Public().Address()
+	assert.Equal(t, http.StatusBadRequest, gatewayCall(t, server, http.MethodGet, "/v1/addresses/"+address.Hex()+"/balance", nil, nil))
+	assert.Equal(t, http.StatusNotFound, gatewayCall(t, server, http.MethodGet, "/v1/addresses/"+address.String()+"/other", nil, nil))
+
+	// clients of the gateway are subject to the bans of the node
+	require.Nil(t, n.bans.Ban("127.0.0.1", time.Now().Add(time.Hour)))
+	body, err := MarshalJSON(signedTransaction())
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, gatewayCall(t, server, http.MethodPost, "/v1/transactions", body, nil))
+}
+
+func TestGatewayStartedByNode(t *testing.T) {
+	httpAddr := freeAddr(t)
+	startTestNode(t, ServerConfig{HTTPListenAddr: httpAddr})
+
+	resp, err := http.Get("http://" + httpAddr + "/v1/blocks/height/0")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
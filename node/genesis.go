@@ -0,0 +1,223 @@
+package node
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/CaiqueRibeiro/blocker/util"
+)
+
+const DefaultChainID = "blocker-dev"
+
+// Coins assigned to an address in the genesis block
+type Allocation struct {
+	Address string `json:"address"` // bech32 encoded (blk1...)
+	Amount  int64  `json:"amount"`
+}
+
+// Parameters of the reward paid to validators. Reserved: the blocks do not pay rewards yet, so they must be zero
+type RewardParams struct {
+	BlockReward     int64 `json:"blockReward"`
+	HalvingInterval int   `json:"halvingInterval"` // blocks until the reward is halved. Zero never halves it
+}
+
+// Parameters of a proof-of-work chain
+type ProofOfWorkParams struct {
+	Bits             uint32 `json:"bits"`             // target of the genesis block (compact format), the easiest one accepted
+	RetargetInterval int    `json:"retargetInterval"` // blocks between the adjustments of the target to the block time
+}
+
+/*
+Parameters of the changes of the validator set of a proof-of-authority chain, made by transactions.
+The changes of the blocks of an epoch take effect in the first block of the next epoch
+*/
+type StakingParams struct {
+	EpochLength int   `json:"epochLength"` // blocks of an epoch
+	MinStake    int64 `json:"minStake"`    // coins locked by a validator joining the set, in the first output of its transaction
+}
+
+/*
+Specification of the first block of the chain and of the parameters of the chain.
+
+The genesis block is deterministic: the same specification always produces the same block (and hash),
+so every node of a network must be started with the same specification
+*/
+type Genesis struct {
+	ChainID     string        `json:"chainId"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Allocations []Allocation  `json:"allocations"`
+	Validators  []string      `json:"validators"` // hex encoded public keys of the initial validators
+	BlockTime   util.Duration `json:"blockTime"`
+	Reward      RewardParams  `json:"reward"`
+	/*
+		Last height whose blocks may spend outputs sent to legacy addresses (the public key truncated), with
+		the key of the address. Zero accepts them at any height, so chains started before hashed addresses keep working
+	*/
+	LegacyAddressHeight int `json:"legacyAddressHeight,omitempty"`
+	// blocks are mined by any key instead of created by the validators. Nil does not use proof-of-work
+	ProofOfWork *ProofOfWorkParams `json:"proofOfWork,omitempty"`
+	// validators join, leave and rotate their keys with transactions. Nil keeps the validators of the genesis
+	Staking *StakingParams `json:"staking,omitempty"`
+}
+
+/*
+Genesis of the development chain: the whole allocation belongs to the key of GenesisSeed.
+It is sent to the legacy address of the key, so the block of the development chain stays the same
+*/
+func DefaultGenesis() *Genesis {
+	privKey := crypto.NewPrivateKeyFromString(GenesisSeed)
+	return &Genesis{
+		ChainID:   DefaultChainID,
+		Timestamp: time.Unix(0, 0).UTC(),
+		Allocations: []Allocation{
+			{
+				Address: privKey.Public().LegacyAddress().String(),
+				Amount:  1000,
+			},
+		},
+		Validators: []string{},
+		BlockTime:  util.Duration(BLOCK_TIME),
+	}
+}
+
+// Loads a genesis specification from a JSON file
+func LoadGenesis(path string) (*Genesis, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g := &Genesis{}
+	if err := json.Unmarshal(b, g); err != nil {
+		return nil, fmt.Errorf("could not parse genesis %s: %w", path, err)
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+/*
+Hash of the specification, exchanged by the nodes in the handshake.
+The genesis block only commits to the timestamp and the allocations, so nodes with different parameters
+(validators, block time, consensus) would otherwise share the same genesis block
+*/
+func (g *Genesis) Hash() []byte {
+	spec := *g
+	spec.Timestamp = g.Timestamp.UTC()
+	b, err := json.Marshal(spec)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.Sum256(b)
+	return hash[:]
+}
+
+func (g *Genesis) Save(path string) error {
+	b, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func (g *Genesis) Validate() error {
+	errs := []error{}
+	if g.ChainID == "" {
+		errs = append(errs, fmt.Errorf("chainId is required"))
+	}
+	if len(g.Allocations) == 0 {
+		errs = append(errs, fmt.Errorf("at least one allocation is required"))
+	}
+	for _, alloc := range g.Allocations {
+		if _, err := crypto.ParseAddress(alloc.Address); err != nil {
+			errs = append(errs, fmt.Errorf("invalid allocation address: %w", err))
+		}
+		if alloc.Amount <= 0 {
+			errs = append(errs, fmt.Errorf("allocation to %s must be positive", alloc.Address))
+		}
+	}
+	for _, validator := range g.Validators {
+		if b, err := hex.DecodeString(validator); err != nil || len(b) != crypto.PubKeyLen {
+			errs = append(errs, fmt.Errorf("invalid validator public key %q", validator))
+		}
+	}
+	if g.BlockTime <= 0 {
+		errs = append(errs, fmt.Errorf("blockTime must be positive"))
+	}
+	if g.LegacyAddressHeight < 0 {
+		errs = append(errs, fmt.Errorf("legacyAddressHeight cannot be negative"))
+	}
+	if g.Reward != (RewardParams{}) { // rejected instead of ignored, so a chain does not expect rewards that are not paid
+		errs = append(errs, fmt.Errorf("reward params are not supported yet: the blocks do not pay rewards"))
+	}
+	if pow := g.ProofOfWork; pow != nil {
+		if len(g.Validators) > 0 {
+			errs = append(errs, fmt.Errorf("a proof-of-work chain cannot have validators"))
+		}
+		if target := types.CompactToTarget(pow.Bits); target.Sign() <= 0 || target.BitLen() > 256 {
+			errs = append(errs, fmt.Errorf("invalid proof-of-work bits %08x", pow.Bits))
+		}
+		if pow.RetargetInterval < 2 { // the time of an interval is measured between its first and last blocks
+			errs = append(errs, fmt.Errorf("retargetInterval must be at least 2"))
+		}
+	}
+	if staking := g.Staking; staking != nil {
+		if len(g.Validators) == 0 {
+			errs = append(errs, fmt.Errorf("staking requires initial validators"))
+		}
+		if staking.EpochLength <= 0 {
+			errs = append(errs, fmt.Errorf("epochLength must be positive"))
+		}
+		if staking.MinStake < 0 {
+			errs = append(errs, fmt.Errorf("minStake cannot be negative"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/*
+Creates the genesis block:
+ 1. A header with height 0, the timestamp of the specification and the proof-of-work bits (if any)
+ 2. A transaction without inputs with one output per allocation, in the order of the specification
+ 3. Signed with the key of GenesisSeed, which is public: the genesis block is trusted by its hash, not by its signer
+*/
+func (g *Genesis) Block() (*proto.Block, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	var timestamp int64
+	if !g.Timestamp.IsZero() {
+		timestamp = g.Timestamp.UnixNano()
+	}
+	block := &proto.Block{
+		Header: &proto.Header{
+			Version:   BlockVersion,
+			Timestamp: timestamp,
+		},
+	}
+	if g.ProofOfWork != nil {
+		block.Header.Bits = g.ProofOfWork.Bits
+	}
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs:  []*proto.TxInput{},
+	}
+	for _, alloc := range g.Allocations {
+		address, _ := crypto.ParseAddress(alloc.Address)
+		tx.Outputs = append(tx.Outputs, &proto.TxOutput{
+			Amount:  alloc.Amount,
+			Address: address.Bytes(),
+		})
+	}
+	block.Transactions = append(block.Transactions, tx)
+	types.SignBlock(crypto.NewPrivateKeyFromString(GenesisSeed), block)
+	return block, nil
+}
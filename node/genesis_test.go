@@ -0,0 +1,149 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Overrides of the genesis created by testGenesis
+type genesisOption func(*Genesis)
+
+// Genesis of a test chain with one validator (a random key), changed by the options
+func testGenesis(opts ...genesisOption) *Genesis {
+	genesis := &Genesis{
+		ChainID:   "blocker-test",
+		Timestamp: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Allocations: []Allocation{
+			{Address: crypto.GeneratePrivateKey().Public().Address().String(), Amount: 500},
+			{Address: crypto.GeneratePrivateKey().Public().Address().String(), Amount: 700},
+		},
+		Validators: []string{hex.EncodeToString(crypto.GeneratePrivateKey().Public().Bytes())},
+		BlockTime:  util.Duration(time.Second),
+	}
+	for _, opt := range opts {
+		opt(genesis)
+	}
+	return genesis
+}
+
+// Proof of authority with the keys as validators (no validators without keys)
+func withValidators(keys ...*crypto.PrivateKey) genesisOption {
+	return func(g *Genesis) {
+		g.Validators = nil
+		for _, key := range keys {
+			g.Validators = append(g.Validators, hex.EncodeToString(key.Public().Bytes()))
+		}
+	}
+}
+
+func withBlockTime(blockTime time.Duration) genesisOption {
+	return func(g *Genesis) {
+		g.BlockTime = util.Duration(blockTime)
+	}
+}
+
+// Proof of work with an easy target, instead of validators
+func withProofOfWork(retargetInterval int) genesisOption {
+	return func(g *Genesis) {
+		g.Validators = nil
+		g.ProofOfWork = &ProofOfWorkParams{Bits: easyBits, RetargetInterval: retargetInterval}
+	}
+}
+
+// Validators joining and leaving with transactions, allocating two outputs to the funder of their stakes
+func withStaking(epochLength int, funder *crypto.PrivateKey) genesisOption {
+	return func(g *Genesis) {
+		g.Allocations = []Allocation{
+			{Address: funder.Public().Address().String(), Amount: 1000},
+			{Address: funder.Public().Address().String(), Amount: 500},
+		}
+		g.Staking = &StakingParams{EpochLength: epochLength, MinStake: 100}
+	}
+}
+
+func TestDefaultGenesisBlock(t *testing.T) {
+	// the default genesis keeps the block of the development chain
+	block, err := DefaultGenesis().Block()
+	require.Nil(t, err)
+	assert.Equal(t, "daa8237c2240a63a13e4939b5e36ed2f1f0ea3ef48e9e74b2acd7aabdd5cf088", hex.EncodeToString(types.HashBlock(block)))
+	assert.True(t, types.VerifyBlock(block))
+}
+
+func TestGenesisDeterministic(t *testing.T) {
+	var (
+		genesis = testGenesis()
+		path    = filepath.Join(t.TempDir(), "genesis.json")
+	)
+	require.Nil(t, genesis.Save(path))
+	loaded, err := LoadGenesis(path)
+	require.Nil(t, err)
+	assert.Equal(t, genesis, loaded)
+
+	block, err := genesis.Block()
+	require.Nil(t, err)
+	loadedBlock, err := loaded.Block()
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(block), types.HashBlock(loadedBlock))
+	assert.Equal(t, genesis.Timestamp.UnixNano(), block.Header.Timestamp)
+
+	// any change in the specification changes the block
+	genesis.Allocations[0].Amount++
+	changed, err := genesis.Block()
+	require.Nil(t, err)
+	assert.NotEqual(t, types.HashBlock(block), types.HashBlock(changed))
+}
+
+func TestGenesisValidate(t *testing.T) {
+	genesis := testGenesis()
+	genesis.ChainID = ""
+	genesis.Allocations = append(genesis.Allocations, Allocation{Address: "abcd", Amount: 0})
+	genesis.Validators = append(genesis.Validators, "not a key")
+	genesis.BlockTime = 0
+	genesis.ProofOfWork = &ProofOfWorkParams{Bits: 0x1d800000}
+	genesis.Staking = &StakingParams{MinStake: -1}
+	genesis.Reward = RewardParams{BlockReward: 10}
+
+	err := genesis.Validate()
+	require.NotNil(t, err)
+	for _, msg := range []string{"chainId", "abcd", "must be positive", "not a key", "blockTime", "cannot have validators", "1d800000", "retargetInterval", "epochLength", "minStake", "reward params"} {
+		assert.ErrorContains(t, err, msg)
+	}
+	_, err = NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	assert.NotNil(t, err)
+}
+
+func TestChainWithGenesis(t *testing.T) {
+	genesis := testGenesis()
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	assert.Equal(t, genesis, chain.Genesis())
+	for _, alloc := range genesis.Allocations {
+		address, err := crypto.ParseAddress(alloc.Address)
+		require.Nil(t, err)
+		balance, err := chain.GetBalance(address.Bytes())
+		require.Nil(t, err)
+		assert.Equal(t, alloc.Amount, balance)
+	}
+
+	n := NewNode(ServerConfig{ListenAddr: ":3000", Genesis: genesis})
+	assert.Equal(t, time.Second, n.BlockTime)
+}
+
+func TestHandshakeOtherChain(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000", Genesis: testGenesis()})
+		remote = crypto.GeneratePrivateKey()
+	)
+	v, _ := signedVersion(t, n, remote, ":4000") // version of a node in the default chain
+	_, err := n.Handshake(context.Background(), v)
+	assert.ErrorContains(t, err, "chain")
+}
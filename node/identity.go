@@ -0,0 +1,100 @@
+package node
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	nonceLen        = 32
+	challengeExpiry = 30 * time.Second
+	maxChallenges   = 4096 // challenges issued in the last challengeExpiry, consumed or not
+)
+
+var ErrTooManyChallenges = errors.New("too many handshake challenges")
+
+// Node IDs are the hex encoded public identity key of the node
+func NodeIDFromPublicKey(pubKey []byte) string {
+	return hex.EncodeToString(pubKey)
+}
+
+func newNonce() []byte {
+	nonce := make([]byte, nonceLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		panic(err)
+	}
+	return nonce
+}
+
+type challenge struct {
+	remoteNonce []byte // nonce sent by the remote node, that we must sign in our handshake response
+	expiresAt   time.Time
+}
+
+/*
+Keeps the nonces issued to remote nodes that are waiting for a handshake.
+
+Each nonce can be used only once, so a signed version cannot be replayed by someone else.
+The challenges are kept in the order they were issued (which is also the order they expire), so the expired ones
+are dropped from the front, and the store holds at most maxChallenges
+*/
+type ChallengeStore struct {
+	lock       sync.Mutex
+	challenges map[string]challenge
+	issued     []issuedChallenge
+}
+
+type issuedChallenge struct {
+	key       string
+	expiresAt time.Time
+}
+
+func NewChallengeStore() *ChallengeStore {
+	return &ChallengeStore{
+		challenges: make(map[string]challenge),
+	}
+}
+
+// Creates a new nonce to be signed by the remote node, keeping the nonce the remote node wants us to sign
+func (s *ChallengeStore) Issue(remoteNonce []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	expired := 0
+	for expired < len(s.issued) && now.After(s.issued[expired].expiresAt) {
+		delete(s.challenges, s.issued[expired].key)
+		expired++
+	}
+	s.issued = s.issued[expired:]
+	if len(s.issued) >= maxChallenges {
+		return nil, ErrTooManyChallenges
+	}
+	nonce := newNonce()
+	key := hex.EncodeToString(nonce)
+	s.challenges[key] = challenge{
+		remoteNonce: remoteNonce,
+		expiresAt:   now.Add(challengeExpiry),
+	}
+	s.issued = append(s.issued, issuedChallenge{key: key, expiresAt: now.Add(challengeExpiry)})
+	return nonce, nil
+}
+
+// Removes the nonce from the store, returning the remote nonce associated with it
+func (s *ChallengeStore) Consume(nonce []byte) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := hex.EncodeToString(nonce)
+	c, ok := s.challenges[key]
+	if !ok {
+		return nil, false
+	}
+	delete(s.challenges, key)
+	if time.Now().After(c.expiresAt) {
+		return nil, false
+	}
+	return c.remoteNonce, true
+}
@@ -0,0 +1,148 @@
+package node
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+/*
+JSON encoding of the messages used by the HTTP gateway and the CLI.
+
+It is the protojson encoding, except for the bytes fields: addresses are bech32 encoded (blk1...)
+and the other ones (hashes, keys and signatures) hex encoded, instead of base64
+*/
+func MarshalJSON(m pb.Message) ([]byte, error) {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := decodeObject(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := convertBytesFields(m.ProtoReflect().Descriptor(), obj, encodeBytes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+// Decodes a message encoded by MarshalJSON
+func UnmarshalJSON(b []byte, m pb.Message) error {
+	obj, err := decodeObject(b)
+	if err != nil {
+		return err
+	}
+	if err := convertBytesFields(m.ProtoReflect().Descriptor(), obj, decodeBytes); err != nil {
+		return err
+	}
+	b, err = json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(b, m)
+}
+
+func decodeObject(b []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber() // keeps the numbers exactly as they were written
+	obj := map[string]any{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Replaces the values of the bytes fields of the JSON object (and of its nested messages) with conv
+func convertBytesFields(md protoreflect.MessageDescriptor, obj map[string]any, conv bytesConverter) error {
+	for key, value := range obj {
+		fd := md.Fields().ByJSONName(key)
+		if fd == nil {
+			fd = md.Fields().ByTextName(key)
+		}
+		if fd == nil { // unknown fields are reported by protojson
+			continue
+		}
+		var err error
+		switch {
+		case fd.IsMap():
+			if values, ok := value.(map[string]any); ok {
+				for k, v := range values {
+					if values[k], err = convertValue(fd.MapValue(), v, conv); err != nil {
+						return fmt.Errorf("%s: %w", key, err)
+					}
+				}
+			}
+		case fd.IsList():
+			if values, ok := value.([]any); ok {
+				for i, v := range values {
+					if values[i], err = convertValue(fd, v, conv); err != nil {
+						return fmt.Errorf("%s: %w", key, err)
+					}
+				}
+			}
+		default:
+			if obj[key], err = convertValue(fd, value, conv); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func convertValue(fd protoreflect.FieldDescriptor, value any, conv bytesConverter) (any, error) {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		if s, ok := value.(string); ok {
+			return conv(fd, s)
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if obj, ok := value.(map[string]any); ok {
+			return obj, convertBytesFields(fd.Message(), obj, conv)
+		}
+	}
+	return value, nil
+}
+
+// Converts the value of a bytes field between the protojson (base64) and MarshalJSON encodings
+type bytesConverter func(fd protoreflect.FieldDescriptor, s string) (string, error)
+
+// Bytes fields holding addresses are named "address" in every message
+func isAddressField(fd protoreflect.FieldDescriptor) bool {
+	return fd.Name() == "address"
+}
+
+func encodeBytes(fd protoreflect.FieldDescriptor, s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	if isAddressField(fd) && len(b) == crypto.AddressLen {
+		return crypto.AddressFromBytes(b).String(), nil
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Decodes the values written by encodeBytes: only address fields of AddressLen bytes are bech32 encoded
+func decodeBytes(fd protoreflect.FieldDescriptor, s string) (string, error) {
+	b, err := hex.DecodeString(s)
+	switch {
+	case isAddressField(fd) && (err != nil || len(b) == crypto.AddressLen):
+		// an address is only accepted with its checksum, never as raw hex
+		address, err := crypto.ParseAddress(s)
+		if err != nil {
+			return "", err
+		}
+		b = address.Bytes()
+	case err != nil:
+		return "", fmt.Errorf("invalid hex value %q", s)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,20 @@
+package node
+
+import "sync/atomic"
+
+// Counters of the requests rejected by the node to protect itself
+type Metrics struct {
+	RejectedPeerRate   atomic.Uint64 // requests over the rate limit of the peer
+	RejectedGlobalRate atomic.Uint64 // requests over the global rate limit
+	RejectedBanned     atomic.Uint64 // requests from banned peers
+	DroppedBroadcasts  atomic.Uint64 // broadcasts not started because too many were running
+}
+
+func (m *Metrics) Snapshot() map[string]uint64 {
+	return map[string]uint64{
+		"rejected_peer_rate":   m.RejectedPeerRate.Load(),
+		"rejected_global_rate": m.RejectedGlobalRate.Load(),
+		"rejected_banned":      m.RejectedBanned.Load(),
+		"dropped_broadcasts":   m.DroppedBroadcasts.Load(),
+	}
+}
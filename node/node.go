@@ -1,10 +1,17 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/CaiqueRibeiro/blocker/crypto"
@@ -12,18 +19,31 @@ import (
 	"github.com/CaiqueRibeiro/blocker/types"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
-const BLOCK_TIME = 5 * time.Second
+const (
+	BLOCK_TIME            = 5 * time.Second // default time between blocks created by the validator
+	DefaultMaxMempoolTxs  = 10000
+	httpReadHeaderTimeout = 10 * time.Second
+	shutdownTimeout       = 5 * time.Second // time given to running calls to finish before the server is closed
+)
 
 type Mempool struct {
-	lock sync.RWMutex
-	txx  map[string]*proto.Transaction
+	lock   sync.RWMutex
+	maxTxs int // max pending transactions. Zero has no limit
+	txx    map[string]*proto.Transaction
+	spent  map[string]string // hashes of the pending transactions by the outputs they spend
+	events *EventBus         // receives the transactions added to the mempool (optional)
 }
 
 func NewMemPool() *Mempool {
-	return &Mempool{txx: make(map[string]*proto.Transaction)}
+	return &Mempool{
+		txx:   make(map[string]*proto.Transaction),
+		spent: make(map[string]string),
+	}
 }
 
 func (m *Mempool) Clear() []*proto.Transaction {
@@ -36,6 +56,7 @@ func (m *Mempool) Clear() []*proto.Transaction {
 		txx[it] = v
 		it++
 	}
+	m.spent = make(map[string]string)
 	return txx
 }
 
@@ -45,121 +66,1095 @@ func (m *Mempool) Len() int {
 	return len(m.txx)
 }
 
+// Returns the pending transactions sorted by hash, keeping them in the mempool
+func (m *Mempool) Transactions() []*proto.Transaction {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	hashes := make([]string, 0, len(m.txx))
+	for hash := range m.txx {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	txx := make([]*proto.Transaction, len(hashes))
+	for i, hash := range hashes {
+		txx[i] = m.txx[hash]
+	}
+	return txx
+}
+
+// Verifies if the mempool reached its max number of transactions
+func (m *Mempool) Full() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.maxTxs > 0 && len(m.txx) >= m.maxTxs
+}
+
 func (m *Mempool) Has(tx *proto.Transaction) bool {
+	return m.HasHash(hex.EncodeToString(types.HashTransaction(tx)))
+}
+
+func (m *Mempool) HasHash(hash string) bool {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	hash := hex.EncodeToString(types.HashTransaction(tx))
 	_, ok := m.txx[hash]
 	return ok
 }
 
+/*
+Adds the transaction to the mempool. Returns false if it is already in the mempool,
+if it spends an output already spent by another pending transaction or if the mempool is full
+*/
 func (m *Mempool) Add(tx *proto.Transaction) bool {
 	if m.Has(tx) {
 		return false
 	}
+	if !m.add(tx) {
+		return false
+	}
+	m.events.Publish(Event{Tx: tx})
+	return true
+}
+
+func (m *Mempool) add(tx *proto.Transaction) bool {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	hash := hex.EncodeToString(types.HashTransaction(tx))
+	if _, ok := m.txx[hash]; ok {
+		return false
+	}
+	if m.maxTxs > 0 && len(m.txx) >= m.maxTxs {
+		return false
+	}
+	for _, input := range tx.Inputs {
+		if _, ok := m.spent[outputKey(input.PrevTxHash, input.PrevOutIndex)]; ok {
+			return false
+		}
+	}
+	for _, input := range tx.Inputs {
+		m.spent[outputKey(input.PrevTxHash, input.PrevOutIndex)] = hash
+	}
 	m.txx[hash] = tx
 	return true
 }
 
+/*
+Removes the transactions added to the block and the pending transactions that conflict with them
+(spending the same outputs). Returns the hashes of the conflicting transactions
+*/
+func (m *Mempool) RemoveBlock(b *proto.Block) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	conflicts := []string{}
+	for _, tx := range b.Transactions {
+		hash := hex.EncodeToString(types.HashTransaction(tx))
+		m.remove(hash)
+		for _, input := range tx.Inputs {
+			if pending, ok := m.spent[outputKey(input.PrevTxHash, input.PrevOutIndex)]; ok {
+				m.remove(pending)
+				conflicts = append(conflicts, pending)
+			}
+		}
+	}
+	return conflicts
+}
+
+// Removes the transaction and the outputs it spends (must be called with the lock held)
+func (m *Mempool) remove(hash string) {
+	tx, ok := m.txx[hash]
+	if !ok {
+		return
+	}
+	delete(m.txx, hash)
+	for _, input := range tx.Inputs {
+		delete(m.spent, outputKey(input.PrevTxHash, input.PrevOutIndex))
+	}
+}
+
 type ServerConfig struct {
-	Version    string
-	ListenAddr string
-	PrivateKey *crypto.PrivateKey
+	Version        string
+	ListenAddr     string
+	BootstrapNodes []string // addresses of the nodes to connect with on startup
+	PrivateKey     *crypto.PrivateKey
+	// identity key used to authenticate the node with its peers. A new one is generated if empty
+	NodeKey *crypto.PrivateKey
+	// node IDs allowed to connect with the node (permissioned deployments). Empty allows any node
+	AllowedPeers []string
+	// encrypts (and optionally mutually authenticates) the connections with other nodes. Nil uses insecure connections
+	TLS *TLSConfig
+	// file where banned peers are persisted. Empty keeps the ban list only in memory
+	BanListPath string
+	// how long a misbehaving peer stays banned. Zero uses DefaultBanDuration
+	BanDuration time.Duration
+	// limits of requests per second received by the node service
+	RateLimit RateLimitConfig
+	// max size in bytes of the messages received and sent. Zero uses DefaultMaxMsgSize
+	MaxMsgSize int
+	// max broadcasts running at the same time. Zero uses DefaultMaxBroadcasts
+	MaxBroadcasts int
+	// address of the HTTP gateway (JSON API). Empty does not start the gateway
+	HTTPListenAddr string
+	// genesis block and parameters of the chain. Nil uses DefaultGenesis
+	Genesis *Genesis
+	// time between the blocks created by the validator. Zero uses the block time of the genesis
+	BlockTime time.Duration
+	// rules to create and accept the blocks. Nil uses the consensus of the genesis
+	Consensus Consensus
+	// max transactions waiting in the mempool. Zero uses DefaultMaxMempoolTxs
+	MaxMempoolTxs int
+	// level of the logger (debug, info, warn or error). Empty uses debug
+	LogLevel string
+	// format of the logs: console or json. Empty uses console
+	LogFormat string
+	// address of the admin service. Empty serves it in ListenAddr, together with the other services
+	AdminListenAddr string
+	// token required in the authorization metadata of the admin calls ("Bearer <token>").
+	// Empty only accepts the admin calls made from the loopback interface
+	AdminToken string
+}
+
+type remotePeer struct {
+	conn    *grpc.ClientConn
+	client  proto.NodeClient
+	version *proto.Version
+	height  int32 // last height known of the peer (guarded by the peer lock)
 }
 
 type Node struct {
 	ServerConfig
-	logger   *zap.SugaredLogger
-	peerLock sync.RWMutex
-	peers    map[proto.NodeClient]*proto.Version
-	mempool  *Mempool
+	logger      *zap.SugaredLogger
+	logLevel    zap.AtomicLevel // level of the logger, changed at runtime by the admin service
+	peerLock    sync.RWMutex
+	peers       map[string]*remotePeer // connected peers by node ID
+	connIDs     map[string]string      // node IDs by the remote address of the connections they handshaked with
+	mempool     *Mempool
+	chain       *Chain
+	rejected    *RejectedTxs
+	events      *EventBus
+	challenges  *ChallengeStore
+	bans        *BanList
+	scores      *PeerScores
+	limiter     *RateLimiter
+	handshakes  *RateLimiter // handshakes started by each caller
+	metrics     *Metrics
+	broadcasts  chan struct{} // slots of the broadcasts running at the same time
+	paused      atomic.Bool   // validator loop is not creating blocks
+	finality    *Finality     // votes of the validators that finalize the blocks (nil if the consensus has no validators)
+	genesisHash []byte        // hash of the genesis specification, compared in the handshakes
+
+	ctx         context.Context // canceled when the node stops
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup // background goroutines (validator loop, bootstrap, broadcasts)
+	stopOnce    sync.Once
+	stopped     chan struct{}
+	server      *grpc.Server
+	adminServer *grpc.Server // only when the admin service has its own address
+	httpServer  *http.Server
+	serverMu    sync.Mutex // guards the servers and the start of background goroutines during the shutdown
 
 	proto.UnimplementedNodeServer
 }
 
-func makeNodeClient(listenAddr string) (proto.NodeClient, error) {
-	c, err := grpc.Dial(listenAddr, grpc.WithInsecure())
+func makeNodeClient(listenAddr string, tlsConfig *TLSConfig) (*remotePeer, error) {
+	creds, err := dialCredentials(tlsConfig)
 	if err != nil {
 		return nil, err
 	}
-	return proto.NewNodeClient(c), nil
+	c, err := grpc.Dial(listenAddr, creds)
+	if err != nil {
+		return nil, err
+	}
+	return &remotePeer{conn: c, client: proto.NewNodeClient(c)}, nil
 }
 
 func NewNode(cfg ServerConfig) *Node {
 	loggerConfig := zap.NewDevelopmentConfig()
 	loggerConfig.EncoderConfig.TimeKey = ""
+	if cfg.LogFormat == "json" {
+		loggerConfig = zap.NewProductionConfig()
+		loggerConfig.Sampling = nil
+	}
+	if cfg.LogLevel != "" {
+		level, err := zap.ParseAtomicLevel(cfg.LogLevel)
+		if err == nil {
+			loggerConfig.Level = level
+		}
+	}
 	logger, _ := loggerConfig.Build()
 
-	return &Node{
-		peers:        make(map[proto.NodeClient]*proto.Version),
+	if cfg.NodeKey == nil {
+		cfg.NodeKey = crypto.GeneratePrivateKey()
+	}
+
+	if cfg.MaxMsgSize <= 0 {
+		cfg.MaxMsgSize = DefaultMaxMsgSize
+	}
+	if cfg.MaxBroadcasts <= 0 {
+		cfg.MaxBroadcasts = DefaultMaxBroadcasts
+	}
+	if cfg.Genesis == nil {
+		cfg.Genesis = DefaultGenesis()
+	}
+	if cfg.BlockTime <= 0 {
+		cfg.BlockTime = time.Duration(cfg.Genesis.BlockTime)
+	}
+	if cfg.MaxMempoolTxs <= 0 {
+		cfg.MaxMempoolTxs = DefaultMaxMempoolTxs
+	}
+
+	bans, err := LoadBanList(cfg.BanListPath)
+	if err != nil {
+		logger.Sugar().Errorw("could not load ban list", "path", cfg.BanListPath, "err", err)
+		bans, _ = LoadBanList("")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var (
+		events  = NewEventBus()
+		mempool = NewMemPool()
+	)
+	if cfg.Consensus == nil {
+		if cfg.Consensus, err = NewConsensus(cfg.Genesis); err != nil {
+			panic(err) // the genesis is validated when it is loaded
+		}
+	}
+	chain, err := NewChainWithConsensus(NewMemoryBlockStore(), NewMemoryTXStore(), cfg.Genesis, cfg.Consensus)
+	if err != nil {
+		panic(err)
+	}
+	mempool.events = events
+	mempool.maxTxs = cfg.MaxMempoolTxs
+	chain.events = events
+
+	n := &Node{
+		ctx:          ctx,
+		cancel:       cancel,
+		stopped:      make(chan struct{}),
+		peers:        make(map[string]*remotePeer),
+		connIDs:      make(map[string]string),
 		logger:       logger.Sugar(),
-		mempool:      NewMemPool(),
+		logLevel:     loggerConfig.Level,
+		mempool:      mempool,
+		chain:        chain,
+		rejected:     NewRejectedTxs(maxRejectedTxs),
+		events:       events,
+		challenges:   NewChallengeStore(),
+		bans:         bans,
+		scores:       NewPeerScores(bans, cfg.BanDuration),
+		limiter:      NewRateLimiter(cfg.RateLimit),
+		handshakes:   newHandshakeLimiter(cfg.RateLimit),
+		metrics:      &Metrics{},
+		broadcasts:   make(chan struct{}, cfg.MaxBroadcasts),
+		genesisHash:  chain.Genesis().Hash(),
 		ServerConfig: cfg,
 	}
+	if _, ok := cfg.Consensus.(*ProofOfAuthority); ok {
+		n.finality = NewFinality(chain, cfg.PrivateKey, n.goBroadcast)
+	}
+	return n
 }
 
-func (n *Node) Start(listenAddr string, bootstrapNodes []string) error {
-	n.ListenAddr = listenAddr
-	options := []grpc.ServerOption{}
+// Returns the ID of the node, derived from its identity key
+func (n *Node) ID() string {
+	return NodeIDFromPublicKey(n.NodeKey.Public().Bytes())
+}
+
+/*
+Starts the node, blocking until it is stopped.
+
+The node stops when ctx is canceled or when Stop is called. In both cases Start returns
+only after the node finished its shutdown
+*/
+func (n *Node) Start(ctx context.Context) error {
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(n.adminAuthInterceptor, n.banInterceptor, n.rateLimitInterceptor),
+		grpc.ChainStreamInterceptor(n.streamInterceptor),
+		grpc.MaxRecvMsgSize(n.MaxMsgSize),
+		grpc.MaxSendMsgSize(n.MaxMsgSize),
+	}
+	if n.TLS != nil {
+		creds, err := n.TLS.ServerCredentials()
+		if err != nil {
+			return err
+		}
+		options = append(options, grpc.Creds(creds))
+	}
 	grpcServer := grpc.NewServer(options...)
-	ln, err := net.Listen("tcp", listenAddr)
+	ln, err := net.Listen("tcp", n.ListenAddr)
 	if err != nil {
 		return err
 	}
 	proto.RegisterNodeServer(grpcServer, n)
-	n.logger.Infow("node started...", "port", n.ListenAddr)
-	if len(bootstrapNodes) > 0 { // if there are bootstrap nodes
-		go n.bootstrapNetwork(bootstrapNodes) // connect with node addresses informed in startup
+	proto.RegisterQueryServer(grpcServer, NewQueryServer(n.chain, n.mempool, n.rejected, n.events))
+
+	var (
+		adminServer *grpc.Server
+		adminLn     net.Listener
+	)
+	if n.AdminListenAddr != "" {
+		adminLn, err = net.Listen("tcp", n.AdminListenAddr)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		adminServer = grpc.NewServer(options...)
+		proto.RegisterAdminServer(adminServer, NewAdminServer(n))
+	} else {
+		if n.AdminToken == "" {
+			n.logger.Warnw("admin service served in the node address without a token, only local calls are accepted", "port", n.ListenAddr)
+		}
+		proto.RegisterAdminServer(grpcServer, NewAdminServer(n))
+	}
+
+	var (
+		httpServer *http.Server
+		httpLn     net.Listener
+	)
+	if n.HTTPListenAddr != "" {
+		httpLn, err = net.Listen("tcp", n.HTTPListenAddr)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		httpServer = &http.Server{Handler: NewGateway(n), ReadHeaderTimeout: httpReadHeaderTimeout}
+	}
+
+	n.serverMu.Lock()
+	if n.ctx.Err() != nil { // stopped before starting
+		n.serverMu.Unlock()
+		ln.Close()
+		if adminLn != nil {
+			adminLn.Close()
+		}
+		if httpLn != nil {
+			httpLn.Close()
+		}
+		return nil
+	}
+	n.server = grpcServer
+	n.adminServer = adminServer
+	n.httpServer = httpServer
+	n.serverMu.Unlock()
+
+	if adminServer != nil {
+		go func() {
+			if err := adminServer.Serve(adminLn); err != nil {
+				n.logger.Errorw("admin service error", "err", err)
+			}
+		}()
+		n.logger.Infow("admin service started...", "port", n.AdminListenAddr)
+	}
+
+	if httpServer != nil {
+		go func() {
+			if err := httpServer.Serve(httpLn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				n.logger.Errorw("http gateway error", "err", err)
+			}
+		}()
+		n.logger.Infow("http gateway started...", "port", n.HTTPListenAddr)
+	}
+
+	go func() { // propagates the cancellation of the caller context
+		select {
+		case <-ctx.Done():
+			n.Stop()
+		case <-n.ctx.Done():
+		}
+	}()
+
+	n.logger.Infow("node started...", "port", n.ListenAddr, "id", n.ID())
+	if len(n.BootstrapNodes) > 0 { // if there are bootstrap nodes
+		n.goBootstrap(n.BootstrapNodes) // connect with node addresses informed in startup
 	}
 	if n.PrivateKey != nil {
-		go n.validatorLoop()
+		if !n.Consensus.Authorized(n.PrivateKey.Public().Bytes()) {
+			n.logger.Warnw("key not authorized by the consensus, blocks will not be created", "pubkey", n.PrivateKey.Public())
+		} else {
+			n.spawn(func() { n.validatorLoop(n.ctx) })
+		}
+	}
+	if n.finality != nil {
+		n.spawn(func() { n.finalityLoop(n.ctx) })
+	}
+	n.spawn(func() { n.revertLoop(n.ctx) })
+	if err := grpcServer.Serve(ln); err != nil {
+		n.Stop()
+		return err
 	}
-	return grpcServer.Serve(ln)
+	<-n.stopped
+	return nil
 }
 
-// receives a connection from an external node, returns own version and add the node to peer list
+/*
+Stops the node:
+ 1. Cancels the node context, ending the validator loop, bootstrap dialing and broadcasts, and closes the subscriptions
+ 2. Gracefully stops the HTTP gateway and the gRPC servers, forcing it if the running calls do not finish in time
+ 3. Waits the background goroutines to finish
+ 4. Closes the connections with the peers and flushes the stores
+*/
+func (n *Node) Stop() {
+	n.stopOnce.Do(func() {
+		n.serverMu.Lock()
+		n.cancel()
+		server := n.server
+		adminServer := n.adminServer
+		httpServer := n.httpServer
+		n.serverMu.Unlock()
+		n.events.Close() // ends the streams, so the server does not wait for them
+		if httpServer != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			if err := httpServer.Shutdown(ctx); err != nil {
+				httpServer.Close()
+			}
+			cancel()
+		}
+		if adminServer != nil {
+			stopServer(adminServer)
+		}
+		if server != nil {
+			stopServer(server)
+		}
+
+		n.wg.Wait()
+
+		n.peerLock.Lock()
+		for id, peer := range n.peers {
+			if err := peer.conn.Close(); err != nil {
+				n.logger.Errorw("could not close peer connection", "id", id, "err", err)
+			}
+			delete(n.peers, id)
+		}
+		n.connIDs = make(map[string]string)
+		n.peerLock.Unlock()
+
+		if err := n.bans.Flush(); err != nil {
+			n.logger.Errorw("could not flush ban list", "err", err)
+		}
+		n.logger.Infow("node stopped", "port", n.ListenAddr)
+		close(n.stopped)
+	})
+}
+
+// Gracefully stops the server, forcing it if the running calls do not finish in time
+func stopServer(server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		server.Stop()
+	}
+}
+
+// first step of the handshake: issues a nonce that the external node must sign with its identity key
+func (n *Node) RequestChallenge(ctx context.Context, c *proto.Challenge) (*proto.Challenge, error) {
+	if len(c.Nonce) != nonceLen {
+		return nil, fmt.Errorf("invalid challenge nonce length (%d)", len(c.Nonce))
+	}
+	if key := n.callerKey(ctx); key != "" && n.handshakes.allow(key) != limitAllowed {
+		n.metrics.RejectedPeerRate.Add(1)
+		return nil, status.Error(codes.ResourceExhausted, "handshake rate limit exceeded")
+	}
+	nonce, err := n.challenges.Issue(c.Nonce)
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return &proto.Challenge{Nonce: nonce}, nil
+}
+
+/*
+receives a connection from an external node, returns own version and add the node to peer list
+
+The version of the external node must be signed over the nonce issued in RequestChallenge,
+and the returned version is signed over the nonce sent by the external node
+*/
 func (n *Node) Handshake(ctx context.Context, v *proto.Version) (*proto.Version, error) {
-	c, err := makeNodeClient(v.ListenAddr)
+	remoteNonce, ok := n.challenges.Consume(v.Nonce)
+	if !ok {
+		return nil, fmt.Errorf("unknown or expired handshake challenge")
+	}
+	if !types.VerifyVersion(v) {
+		return nil, fmt.Errorf("invalid version signature")
+	}
+	if v.ChainId != n.chain.Genesis().ChainID {
+		return nil, status.Errorf(codes.FailedPrecondition, "node is in chain %q, expected %q", v.ChainId, n.chain.Genesis().ChainID)
+	}
+	if !bytes.Equal(v.GenesisHash, n.genesisHash) {
+		return nil, status.Errorf(codes.FailedPrecondition, "node has the genesis %x, expected %x", v.GenesisHash, n.genesisHash)
+	}
+	id := NodeIDFromPublicKey(v.PublicKey)
+	if !n.isAllowed(id) {
+		return nil, fmt.Errorf("node %s is not allowed to connect", id)
+	}
+	if n.bans.IsBanned(id) {
+		return nil, status.Errorf(codes.PermissionDenied, "node %s is banned", id)
+	}
+	addr, err := dialBackAddr(ctx, v.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	c, err := makeNodeClient(addr, n.TLS)
 	if err != nil {
 		return nil, err
 	}
-	n.addPeer(c, v)            // add the receiving node to the list of connected peers (two-way connection)
-	return n.getVersion(), nil // returns own version to receiving node to be added in its list of connected peers
+	c.version = v
+	if p, ok := peer.FromContext(ctx); ok { // next calls in this connection come from the authenticated node
+		n.peerLock.Lock()
+		n.connIDs[p.Addr.String()] = id
+		n.peerLock.Unlock()
+	}
+	n.addPeer(id, c)                      // add the receiving node to the list of connected peers (two-way connection)
+	return n.getVersion(remoteNonce), nil // returns own version to receiving node to be added in its list of connected peers
+}
+
+/*
+Returns the address used to dial back a node that handshaked with us, checking the listen address it reported.
+
+The host of the address must be the host of the connection, so a node cannot make us dial somewhere else.
+Addresses without a host (":3000") are dialed in the host of the connection
+*/
+func dialBackAddr(ctx context.Context, listenAddr string) (string, error) {
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid listen address %q: %v", listenAddr, err)
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok { // not called through a connection
+		return listenAddr, nil
+	}
+	remote, ok := p.Addr.(*net.TCPAddr)
+	if !ok {
+		return listenAddr, nil
+	}
+	if ip := net.ParseIP(host); host == "" || ip.IsUnspecified() {
+		return net.JoinHostPort(remote.IP.String(), port), nil
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "could not resolve listen address %q: %v", listenAddr, err)
+	}
+	for _, ip := range ips {
+		if ip.Equal(remote.IP) {
+			return listenAddr, nil
+		}
+	}
+	return "", status.Errorf(codes.InvalidArgument, "listen address %q is not in the host of the connection (%s)", listenAddr, remote.IP)
 }
 
+/*
+Receives a transaction from a client or another node. Valid transactions are added to the mempool
+and broadcasted to the peers. The returned ack informs if the transaction was accepted or why it was rejected
+*/
 func (n *Node) HandleTransaction(ctx context.Context, tx *proto.Transaction) (*proto.Ack, error) {
-	peer, _ := peer.FromContext(ctx)
-	hash := hex.EncodeToString(types.HashTransaction(tx))
-	if n.mempool.Add(tx) {
-		n.logger.Debugw("received tx", "from", peer.Addr, "hash", hash, "we", n.ListenAddr)
-		go func() {
-			if err := n.broadcast(tx); err != nil {
-				n.logger.Errorw("broadcast error", "err", err)
+	key := n.callerKey(ctx)
+	if n.scores.CountTx(key) {
+		n.penalize(key, PenaltyTxFlood, "transaction flood")
+	}
+	hash := types.HashTransaction(tx)
+	if err := checkTransaction(tx); err != nil {
+		n.penalize(key, PenaltyMalformedTx, err.Error())
+		return n.rejectTransaction(hash, err), nil
+	}
+	if err := n.chain.VerifySignatures(tx); err != nil {
+		n.penalize(key, PenaltyInvalidSignature, "invalid transaction signature")
+		return n.rejectTransaction(hash, err), nil
+	}
+	if _, err := n.chain.GetTransaction(hash); err == nil || n.mempool.Has(tx) { // already known
+		return &proto.Ack{Hash: hash, Accepted: true}, nil
+	}
+	if err := n.chain.ValidateTransaction(tx); err != nil {
+		return n.rejectTransaction(hash, err), nil
+	}
+	if n.mempool.Full() {
+		return n.rejectTransaction(hash, fmt.Errorf("mempool is full")), nil
+	}
+	if !n.mempool.Add(tx) {
+		return n.rejectTransaction(hash, fmt.Errorf("transaction spends outputs of a pending transaction")), nil
+	}
+	n.logger.Debugw("received tx", "from", key, "hash", hex.EncodeToString(hash), "we", n.ListenAddr)
+	n.goBroadcast(tx)
+	return &proto.Ack{Hash: hash, Accepted: true}, nil
+}
+
+func (n *Node) rejectTransaction(hash []byte, err error) *proto.Ack {
+	n.rejected.Add(hex.EncodeToString(hash), err.Error())
+	return &proto.Ack{Hash: hash, Accepted: false, Error: err.Error()}
+}
+
+/*
+Receives a block created by a validator. Valid blocks are added to the chain, and the ones that change the main chain
+are broadcasted to the peers and have their transactions removed from the mempool
+(a block stored in a fork that was not chosen is not relayed)
+*/
+func (n *Node) HandleBlock(ctx context.Context, b *proto.Block) (*proto.Ack, error) {
+	key := n.callerKey(ctx)
+	if b.Header == nil {
+		n.penalize(key, PenaltyInvalidBlock, "block without header")
+		return &proto.Ack{Accepted: false, Error: "block without header"}, nil
+	}
+	hash := types.HashBlock(b)
+	if _, err := n.chain.GetBlockByHash(hash); err == nil { // already known
+		n.updatePeerHeight(key, b.Header.Height)
+		return &proto.Ack{Hash: hash, Accepted: true}, nil
+	}
+	if !types.VerifyBlock(b) {
+		n.penalize(key, PenaltyInvalidBlock, "invalid block signature")
+		return &proto.Ack{Hash: hash, Accepted: false, Error: "invalid block signature"}, nil
+	}
+	n.updatePeerHeight(key, b.Header.Height)
+	changed, err := n.chain.AddBlock(b)
+	if err != nil {
+		// not for blocks on an unknown parent, forks that are not accepted or blocks ahead of the clock of the node
+		if errors.Is(err, ErrInvalidBlock) && !errors.Is(err, ErrFutureBlock) {
+			n.penalize(key, PenaltyInvalidBlock, err.Error())
+		}
+		return &proto.Ack{Hash: hash, Accepted: false, Error: err.Error()}, nil
+	}
+	if !changed {
+		return &proto.Ack{Hash: hash, Accepted: true}, nil
+	}
+	n.removeFromMempool(b)
+	n.logger.Debugw("received block", "hash", hex.EncodeToString(hash), "height", b.Header.Height, "lenTx", len(b.Transactions), "we", n.ListenAddr)
+	n.goBroadcast(b)
+	return &proto.Ack{Hash: hash, Accepted: true}, nil
+}
+
+/*
+Receives a vote of a validator for the finality of a block. New votes are broadcasted to the peers,
+so they reach the validators that are not connected with the voter
+*/
+func (n *Node) HandleVote(ctx context.Context, v *proto.Vote) (*proto.Ack, error) {
+	hash := types.HashVote(v)
+	if n.finality == nil {
+		return &proto.Ack{Hash: hash, Accepted: false, Error: "the chain has no validators voting for finality"}, nil
+	}
+	if !types.VerifyVote(v) {
+		n.penalize(n.callerKey(ctx), PenaltyInvalidSignature, "invalid vote signature")
+		return &proto.Ack{Hash: hash, Accepted: false, Error: "invalid vote signature"}, nil
+	}
+	added, err := n.finality.AddVote(v)
+	if err != nil {
+		return &proto.Ack{Hash: hash, Accepted: false, Error: err.Error()}, nil
+	}
+	if added {
+		n.goBroadcast(v)
+	}
+	return &proto.Ack{Hash: hash, Accepted: true}, nil
+}
+
+// Removes the transactions of the block from the mempool, rejecting the pending transactions that conflict with them
+func (n *Node) removeFromMempool(b *proto.Block) {
+	for _, hash := range n.mempool.RemoveBlock(b) {
+		n.rejected.Add(hash, "outputs spent by a transaction in a block")
+	}
+}
+
+// Verifies that the transaction is well formed, so its signatures can be verified
+func checkTransaction(tx *proto.Transaction) error {
+	if len(tx.Inputs) == 0 {
+		return fmt.Errorf("transaction has no inputs")
+	}
+	if len(tx.Outputs) == 0 {
+		return fmt.Errorf("transaction has no outputs")
+	}
+	for i, input := range tx.Inputs {
+		if len(input.PrevTxHash) != 32 {
+			return fmt.Errorf("input %d has an invalid previous tx hash", i)
+		}
+		if len(input.PublicKey) != crypto.PubKeyLen {
+			return fmt.Errorf("input %d has an invalid public key", i)
+		}
+		if len(input.Signature) != crypto.SignatureLen {
+			return fmt.Errorf("input %d has an invalid signature", i)
+		}
+	}
+	for i, output := range tx.Outputs {
+		if output.Amount <= 0 {
+			return fmt.Errorf("output %d has an invalid amount (%d)", i, output.Amount)
+		}
+		if len(output.Address) != crypto.AddressLen {
+			return fmt.Errorf("output %d has an invalid address", i)
+		}
+	}
+	return nil
+}
+
+/*
+Returns the key used to score the caller of a request.
+
+Connections that went through a handshake are scored by the node ID, any other connection by its remote host
+*/
+func (n *Node) callerKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	addr := p.Addr.String()
+	n.peerLock.RLock()
+	id, ok := n.connIDs[addr]
+	n.peerLock.RUnlock()
+	if ok {
+		return id
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+/*
+Decrements the score of a misbehaving peer, disconnecting it if it gets banned.
+
+Local callers are not penalized, as all the tools running on the node host share the same key,
+and neither are the peers of the allowlist, which are trusted by the operator
+*/
+func (n *Node) penalize(key string, penalty int, reason string) {
+	if key == "" || n.isExempt(key) {
+		return
+	}
+	banned, err := n.scores.Penalize(key, penalty)
+	if err != nil {
+		n.logger.Errorw("could not persist ban list", "err", err)
+	}
+	n.logger.Debugw("peer penalized", "peer", key, "penalty", penalty, "reason", reason, "score", n.scores.Score(key))
+	if banned {
+		n.logger.Infow("peer banned", "peer", key, "reason", reason)
+		n.deletePeer(key)
+	}
+}
+
+// Verifies if the peer is exempt from penalties: a loopback host or a node ID of the allowlist
+func (n *Node) isExempt(key string) bool {
+	if ip := net.ParseIP(key); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	return len(n.AllowedPeers) > 0 && n.isAllowed(key)
+}
+
+// Rejects any call to the node service from banned peers
+func (n *Node) banInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if strings.HasPrefix(info.FullMethod, "/Node/") {
+		if err := n.checkBanned(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// Rejects calls to the node service over the rate limit of the peer or over the global rate limit
+func (n *Node) rateLimitInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if strings.HasPrefix(info.FullMethod, "/Node/") {
+		if err := n.checkRateLimit(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+/*
+Rejects the streams opened by banned peers or over the rate limits.
+
+Streams are held open for as long as the caller wants, so every stream is checked, whatever its service
+*/
+func (n *Node) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := n.checkBanned(ss.Context()); err != nil {
+		return err
+	}
+	if err := n.checkRateLimit(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (n *Node) checkBanned(ctx context.Context) error {
+	key := n.callerKey(ctx)
+	if n.bans.IsBanned(key) {
+		n.metrics.RejectedBanned.Add(1)
+		return status.Errorf(codes.PermissionDenied, "peer %s is banned", key)
+	}
+	return nil
+}
+
+func (n *Node) checkRateLimit(ctx context.Context) error {
+	switch n.limiter.allow(n.callerKey(ctx)) {
+	case limitPeer:
+		n.metrics.RejectedPeerRate.Add(1)
+		return status.Error(codes.ResourceExhausted, "peer rate limit exceeded")
+	case limitGlobal:
+		n.metrics.RejectedGlobalRate.Add(1)
+		return status.Error(codes.ResourceExhausted, "node rate limit exceeded")
+	}
+	return nil
+}
+
+// Broadcasts the message in background, unless the max broadcasts are already running
+func (n *Node) goBroadcast(msg any) {
+	select {
+	case n.broadcasts <- struct{}{}:
+	default:
+		n.metrics.DroppedBroadcasts.Add(1)
+		n.logger.Warnw("too many broadcasts running, dropping broadcast")
+		return
+	}
+	started := n.spawn(func() {
+		defer func() { <-n.broadcasts }()
+		if err := n.broadcast(n.ctx, msg); err != nil {
+			n.logger.Errorw("broadcast error", "err", err)
+		}
+	})
+	if !started {
+		<-n.broadcasts
+	}
+}
+
+// Connects with the nodes in background, until the node stops
+func (n *Node) goBootstrap(addrs []string) {
+	n.spawn(func() {
+		if err := n.bootstrapNetwork(n.ctx, addrs); err != nil && n.ctx.Err() == nil {
+			n.logger.Errorw("bootstrap error", "err", err)
+		}
+	})
+}
+
+// Runs fn in a goroutine that Stop waits for. Returns false if the node is already stopping
+func (n *Node) spawn(fn func()) bool {
+	n.serverMu.Lock()
+	defer n.serverMu.Unlock()
+	if n.ctx.Err() != nil {
+		return false
+	}
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+/*
+Creates the blocks of the validator, clearing all the transactions in the mempool in each one.
+The loop runs every block time or in the interval of the consensus, which decides if the validator
+can create the next block at that time
+*/
+func (n *Node) validatorLoop(ctx context.Context) {
+	interval := n.Consensus.Interval()
+	if interval == 0 {
+		interval = n.BlockTime
+	}
+	n.logger.Infow("starting validator loop", "pubkey", n.PrivateKey.Public(), "blockTime", n.BlockTime, "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			n.logger.Infow("stopping validator loop")
+			return
+		case now := <-ticker.C:
+			if n.paused.Load() { // the pending transactions wait in the mempool until the validator is resumed
+				continue
 			}
-		}()
+			block, err := n.prepareBlock(now)
+			if errors.Is(err, ErrNotReady) {
+				continue
+			}
+			if err != nil {
+				n.logger.Errorw("could not prepare block", "err", err)
+				continue
+			}
+			txx := n.mempool.Clear()
+			n.logger.Debugw("time to create a new block", "lenTx", len(txx))
+			sealCtx, cancel := n.untilNewBlock(ctx)
+			err = n.sealBlock(sealCtx, block, txx)
+			cancel()
+			if err != nil {
+				n.restoreMempool(block.Transactions)
+				if !errors.Is(err, context.Canceled) {
+					n.logger.Errorw("could not create block", "err", err)
+				}
+				continue
+			}
+			if _, err := n.chain.AddBlock(block); err != nil {
+				n.restoreMempool(block.Transactions)
+				n.logger.Errorw("could not add block", "err", err)
+				continue
+			}
+			n.logger.Infow("new block", "hash", hex.EncodeToString(types.HashBlock(block)), "height", block.Header.Height, "lenTx", len(block.Transactions))
+			n.goBroadcast(block)
+		}
+	}
+}
+
+/*
+Context canceled when a block is added to the chain, so the validator stops sealing (mining) a block
+whose parent is no longer the chain tip
+*/
+func (n *Node) untilNewBlock(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := n.events.Subscribe(func(ev Event) bool { return ev.Block != nil })
+	go func() {
+		defer sub.Close()
+		select {
+		case <-sub.Events():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Puts back in the mempool the transactions of a block that could not be sealed or added to the chain
+func (n *Node) restoreMempool(txx []*proto.Transaction) {
+	for _, tx := range txx {
+		n.mempool.add(tx)
+	}
+}
+
+// Puts back in the mempool the transactions of the blocks reverted by a reorg that are still valid
+func (n *Node) revertLoop(ctx context.Context) {
+	sub := n.events.Subscribe(func(ev Event) bool { return ev.Reverted })
+	defer func() { sub.Close() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-sub.Events():
+			if !ok { // dropped for being slow, the transactions of the missed blocks stay out of the mempool
+				if ctx.Err() != nil {
+					return
+				}
+				sub = n.events.Subscribe(func(ev Event) bool { return ev.Reverted })
+				continue
+			}
+			n.readdToMempool(ev.Block)
+		}
+	}
+}
+
+// Adds again to the mempool the transactions of the reverted block that are not in the new chain and are still valid
+func (n *Node) readdToMempool(b *proto.Block) {
+	for _, tx := range b.Transactions {
+		if _, ok := n.chain.GetTransactionLocation(types.HashTransaction(tx)); ok {
+			continue
+		}
+		if err := n.chain.ValidateTransaction(tx); err != nil {
+			n.rejected.Add(hex.EncodeToString(types.HashTransaction(tx)), err.Error())
+			continue
+		}
+		n.mempool.Add(tx)
 	}
-	return &proto.Ack{}, nil
 }
 
-func (n *Node) validatorLoop() {
-	n.logger.Infow("starting validator loop", "pubkey", n.PrivateKey.Public(), "blockTime", BLOCK_TIME)
-	ticker := time.NewTicker(BLOCK_TIME) // process a new block every 5 seconds, clearing all the transactions in the mempool
+// Updates the finality votes when the chain changes and when the rounds time out
+func (n *Node) finalityLoop(ctx context.Context) {
+	sub := n.events.Subscribe(func(ev Event) bool { return ev.Block != nil })
+	defer func() { sub.Close() }()
+	ticker := time.NewTicker(roundCheckInterval)
+	defer ticker.Stop()
 	for {
-		<-ticker.C
-		txx := n.mempool.Clear()
-		n.logger.Debugw("time to create a new block", "lenTx", len(txx))
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-sub.Events():
+			if !ok { // dropped for being slow, the ticker keeps the votes going meanwhile
+				if ctx.Err() != nil {
+					return
+				}
+				sub = n.events.Subscribe(func(ev Event) bool { return ev.Block != nil })
+			}
+			n.finality.Update(time.Now())
+		case now := <-ticker.C:
+			n.finality.Update(now)
+		}
+	}
+}
+
+// Creates a block on top of the last block of the chain, signed with the validator key, with the valid transactions
+func (n *Node) createBlock(txx []*proto.Transaction) (*proto.Block, error) {
+	block, err := n.prepareBlock(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := n.sealBlock(n.ctx, block, txx); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Block without transactions on top of the last block of the chain, with the header prepared by the consensus
+func (n *Node) prepareBlock(timestamp time.Time) (*proto.Block, error) {
+	height := n.chain.Height()
+	prevBlock, err := n.chain.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	block := &proto.Block{
+		Header: &proto.Header{
+			Version:   BlockVersion,
+			Height:    int32(height + 1),
+			PrevHash:  types.HashBlock(prevBlock),
+			Timestamp: timestamp.UnixNano(),
+		},
+		LastCommit: n.chain.LastCommit(),
+	}
+	if err := n.Consensus.Prepare(n.chain, prevBlock.Header, block.Header, n.PrivateKey.Public().Bytes()); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+/*
+Adds the valid transactions to the block and seals it with the validator key.
+The changes of the validator set are applied in order, so conflicting ones are not added to the same block
+*/
+func (n *Node) sealBlock(ctx context.Context, block *proto.Block, txx []*proto.Transaction) error {
+	validators := n.chain.validators.Latest()
+	for _, tx := range txx {
+		err := n.chain.ValidateTransaction(tx) // the chain may have changed since the tx was received
+		if err == nil {
+			var next *ValidatorSet
+			if next, err = n.chain.applyValidatorOp(validators, tx); err == nil {
+				validators = next
+			}
+		}
+		if err != nil {
+			n.rejected.Add(hex.EncodeToString(types.HashTransaction(tx)), err.Error())
+			continue
+		}
+		block.Transactions = append(block.Transactions, tx)
 	}
+	return n.Consensus.Seal(ctx, block, n.PrivateKey)
 }
 
-// Loop through all connected peers and broadcast the message to each one
-func (n *Node) broadcast(msg any) error {
-	for peer := range n.peers {
+/*
+Loop through all connected peers and broadcast the message to each one.
+The peers are copied before the calls, so the lock is not held while waiting for them
+(a peer handling the message may be broadcasting to this node at the same time)
+*/
+func (n *Node) broadcast(ctx context.Context, msg any) error {
+	n.peerLock.RLock()
+	peers := make([]*remotePeer, 0, len(n.peers))
+	for _, peer := range n.peers {
+		peers = append(peers, peer)
+	}
+	n.peerLock.RUnlock()
+	for _, peer := range peers {
 		switch v := msg.(type) {
 		case *proto.Transaction:
-			_, err := peer.HandleTransaction(context.Background(), v)
+			_, err := peer.client.HandleTransaction(ctx, v)
+			if err != nil {
+				return err
+			}
+		case *proto.Block:
+			_, err := peer.client.HandleBlock(ctx, v)
+			if err != nil {
+				return err
+			}
+		case *proto.Vote:
+			_, err := peer.client.HandleVote(ctx, v)
 			if err != nil {
 				return err
 			}
@@ -169,41 +1164,97 @@ func (n *Node) broadcast(msg any) error {
 }
 
 // handshakes with a list of other node addresses and add it in own list of connected peers
-func (n *Node) bootstrapNetwork(addrs []string) error {
+func (n *Node) bootstrapNetwork(ctx context.Context, addrs []string) error {
 	for _, addr := range addrs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if !n.canConnectWith(addr) { // verify if candidate to connection is able to be connected
 			continue
 		}
 		n.logger.Debugw("dialing remote node", "we", n.ListenAddr, "remote", addr)
-		c, v, err := n.dialRemoteWork(addr)
+		c, err := n.dialRemoteWork(ctx, addr)
 		if err != nil {
 			return err
 		}
-		n.addPeer(c, v) // adds the node to the list of connected peers
+		n.addPeer(NodeIDFromPublicKey(c.version.PublicKey), c) // adds the node to the list of connected peers
 	}
 	return nil
 }
 
-// makes handshake with a single address and returns client/version to be added in node peer
-func (n *Node) dialRemoteWork(addr string) (proto.NodeClient, *proto.Version, error) {
-	c, err := makeNodeClient(addr) // connects to an external node address
+/*
+makes handshake with a single address and returns client/version to be added in node peer
+
+ 1. Sends a nonce to the external node and receives the nonce it wants us to sign
+ 2. Sends own version signed over the received nonce
+ 3. Verifies that the returned version was signed over our nonce by the key it claims
+*/
+func (n *Node) dialRemoteWork(ctx context.Context, addr string) (*remotePeer, error) {
+	c, err := makeNodeClient(addr, n.TLS) // connects to an external node address
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	v, err := c.Handshake(context.Background(), n.getVersion()) // sends own version to another node an receives its version from it
+	v, err := n.handshake(ctx, c.client)
 	if err != nil {
-		return nil, nil, err
+		c.conn.Close()
+		return nil, fmt.Errorf("handshake with %s failed: %w", addr, err)
 	}
-	return c, v, nil
+	c.version = v
+	return c, nil
 }
 
-func (n *Node) getVersion() *proto.Version {
-	return &proto.Version{
-		Version:    "blocker-0.1",
-		Height:     0,
-		ListenAddr: n.ListenAddr,
-		PeerList:   n.getPeerList(),
+func (n *Node) handshake(ctx context.Context, c proto.NodeClient) (*proto.Version, error) {
+	nonce := newNonce()
+	challenge, err := c.RequestChallenge(ctx, &proto.Challenge{Nonce: nonce})
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.Handshake(ctx, n.getVersion(challenge.Nonce)) // sends own version to another node an receives its version from it
+	if err != nil {
+		return nil, err
 	}
+	if !bytes.Equal(v.Nonce, nonce) || !types.VerifyVersion(v) {
+		return nil, fmt.Errorf("remote node failed to prove its identity")
+	}
+	if v.ChainId != n.chain.Genesis().ChainID {
+		return nil, fmt.Errorf("remote node is in chain %q, expected %q", v.ChainId, n.chain.Genesis().ChainID)
+	}
+	if !bytes.Equal(v.GenesisHash, n.genesisHash) {
+		return nil, fmt.Errorf("remote node has the genesis %x, expected %x", v.GenesisHash, n.genesisHash)
+	}
+	id := NodeIDFromPublicKey(v.PublicKey)
+	if !n.isAllowed(id) {
+		return nil, fmt.Errorf("node %s is not allowed to connect", id)
+	}
+	return v, nil
+}
+
+// Returns own version signed with the node key over the nonce issued by the remote node
+func (n *Node) getVersion(nonce []byte) *proto.Version {
+	v := &proto.Version{
+		Version:     "blocker-0.1",
+		Height:      int32(n.chain.Height()),
+		ListenAddr:  n.ListenAddr,
+		PeerList:    n.getPeerList(),
+		Nonce:       nonce,
+		ChainId:     n.chain.Genesis().ChainID,
+		GenesisHash: n.genesisHash,
+	}
+	types.SignVersion(n.NodeKey, v)
+	return v
+}
+
+// verify if the node ID is in the allowlist (when there is one)
+func (n *Node) isAllowed(id string) bool {
+	if len(n.AllowedPeers) == 0 {
+		return true
+	}
+	for _, allowed := range n.AllowedPeers {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
 }
 
 /*
@@ -231,8 +1282,8 @@ func (n *Node) getPeerList() []string {
 	n.peerLock.RLock()
 	defer n.peerLock.RUnlock()
 	peers := []string{}
-	for _, version := range n.peers {
-		peers = append(peers, version.ListenAddr)
+	for _, peer := range n.peers {
+		peers = append(peers, peer.version.ListenAddr)
 	}
 	return peers
 }
@@ -240,24 +1291,51 @@ func (n *Node) getPeerList() []string {
 /*
 Gets the client and version of an external node and add it to the list of connected peers.
 
-A node cannot connect with itself nor with a node that is already in the list of connected peers
+A node cannot connect with itself nor with a node that is already in the list of connected peers.
+Returns false when the peer was not added
 */
-func (n *Node) addPeer(c proto.NodeClient, v *proto.Version) {
+func (n *Node) addPeer(id string, c *remotePeer) bool {
 	n.peerLock.Lock()
 	defer n.peerLock.Unlock()
-	n.peers[c] = v
+	_, connected := n.peers[id]
+	if id == n.ID() || connected || n.bans.IsBanned(id) || n.ctx.Err() != nil {
+		c.conn.Close()
+		return false
+	}
+	v := c.version
+	c.height = v.Height
+	n.peers[id] = c
 	// connect to all peers in the received list of peer from other node
 	if len(v.PeerList) > 0 {
-		go n.bootstrapNetwork(v.PeerList)
+		n.goBootstrap(v.PeerList)
 	}
 	n.logger.Debugw("new peer connected",
 		"we", n.ListenAddr,
 		"remoteNode", v.ListenAddr,
+		"id", id,
 		"height", v.Height)
+	return true
 }
 
-func (n *Node) deletePeer(c proto.NodeClient) {
+// Records the height of a block received from the peer, if it is higher than the last one known
+func (n *Node) updatePeerHeight(id string, height int32) {
 	n.peerLock.Lock()
 	defer n.peerLock.Unlock()
-	delete(n.peers, c)
+	if peer, ok := n.peers[id]; ok && height > peer.height {
+		peer.height = height
+	}
+}
+
+func (n *Node) deletePeer(id string) {
+	n.peerLock.Lock()
+	defer n.peerLock.Unlock()
+	if peer, ok := n.peers[id]; ok {
+		peer.conn.Close()
+	}
+	delete(n.peers, id)
+	for addr, connID := range n.connIDs {
+		if connID == id {
+			delete(n.connIDs, addr)
+		}
+	}
 }
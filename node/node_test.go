@@ -0,0 +1,257 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// Runs the challenge step against the node and returns a version signed over the issued nonce
+func signedVersion(t *testing.T, n *Node, key *crypto.PrivateKey, listenAddr string) (*proto.Version, []byte) {
+	nonce := util.RandomHash()
+	challenge, err := n.RequestChallenge(context.Background(), &proto.Challenge{Nonce: nonce})
+	require.Nil(t, err)
+	v := &proto.Version{
+		Version:     "blocker-0.1",
+		ListenAddr:  listenAddr,
+		Nonce:       challenge.Nonce,
+		ChainId:     DefaultChainID,
+		GenesisHash: DefaultGenesis().Hash(),
+	}
+	types.SignVersion(key, v)
+	return v, nonce
+}
+
+// Creates a transaction spending the genesis output: amount to toAddress and the rest back to the genesis address
+func genesisTransaction(t *testing.T, chain *Chain, toAddress []byte, amount int64) *proto.Transaction {
+	privKey := crypto.NewPrivateKeyFromString(GenesisSeed)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   types.HashTransaction(genesis.Transactions[0]),
+				PrevOutIndex: 0,
+				PublicKey:    privKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  amount,
+				Address: toAddress,
+			},
+			{
+				Amount:  genesis.Transactions[0].Outputs[0].Amount - amount,
+				Address: privKey.Public().Address().Bytes(),
+			},
+		},
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+	return tx
+}
+
+func TestHandshake(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		key    = crypto.GeneratePrivateKey()
+		v, our = signedVersion(t, n, key, ":4000")
+	)
+	resp, err := n.Handshake(context.Background(), v)
+	require.Nil(t, err)
+	// the response is signed by the node over the nonce we sent
+	assert.Equal(t, our, resp.Nonce)
+	assert.Equal(t, n.NodeKey.Public().Bytes(), resp.PublicKey)
+	assert.True(t, types.VerifyVersion(resp))
+
+	// the peer is kept by its node ID
+	id := NodeIDFromPublicKey(key.Public().Bytes())
+	require.Contains(t, n.peers, id)
+	assert.Equal(t, []string{":4000"}, n.getPeerList())
+}
+
+func TestHandshakeReplay(t *testing.T) {
+	var (
+		n    = NewNode(ServerConfig{ListenAddr: ":3000"})
+		v, _ = signedVersion(t, n, crypto.GeneratePrivateKey(), ":4000")
+	)
+	_, err := n.Handshake(context.Background(), v)
+	require.Nil(t, err)
+	// the same signed version cannot be used twice
+	_, err = n.Handshake(context.Background(), v)
+	require.NotNil(t, err)
+}
+
+func TestHandshakeListenAddr(t *testing.T) {
+	var (
+		n    = NewNode(ServerConfig{ListenAddr: ":3000"})
+		ctx  = peerContext("10.0.0.1:5000")
+		v, _ = signedVersion(t, n, crypto.GeneratePrivateKey(), "10.0.0.2:4000")
+	)
+	// a node cannot make us dial another host
+	_, err := n.Handshake(ctx, v)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Empty(t, n.peers)
+
+	for listenAddr, want := range map[string]string{
+		"10.0.0.1:4000": "10.0.0.1:4000",
+		":4000":         "10.0.0.1:4000",
+		"0.0.0.0:4000":  "10.0.0.1:4000",
+	} {
+		addr, err := dialBackAddr(ctx, listenAddr)
+		require.Nil(t, err)
+		assert.Equal(t, want, addr)
+	}
+	_, err = dialBackAddr(ctx, "4000")
+	assert.NotNil(t, err)
+}
+
+func TestHandshakeRateLimit(t *testing.T) {
+	var (
+		n   = NewNode(ServerConfig{ListenAddr: ":3000", RateLimit: RateLimitConfig{HandshakeRate: 0.001, HandshakeBurst: 2}})
+		req = &proto.Challenge{Nonce: util.RandomHash()}
+	)
+	for i := 0; i < 2; i++ {
+		_, err := n.RequestChallenge(peerContext("10.0.0.1:5000"), req)
+		require.Nil(t, err)
+	}
+	_, err := n.RequestChallenge(peerContext("10.0.0.1:5001"), req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = n.RequestChallenge(peerContext("10.0.0.2:5000"), req)
+	assert.Nil(t, err)
+}
+
+func TestChallengeStoreCap(t *testing.T) {
+	s := NewChallengeStore()
+	for i := 0; i < maxChallenges; i++ {
+		_, err := s.Issue(util.RandomHash())
+		require.Nil(t, err)
+	}
+	_, err := s.Issue(util.RandomHash())
+	assert.ErrorIs(t, err, ErrTooManyChallenges)
+
+	// expired challenges free the store
+	for i := range s.issued[:10] {
+		s.issued[i].expiresAt = time.Now().Add(-time.Second)
+	}
+	_, err = s.Issue(util.RandomHash())
+	assert.Nil(t, err)
+	assert.Len(t, s.issued, maxChallenges-9)
+	assert.Len(t, s.challenges, maxChallenges-9)
+}
+
+func TestHandshakeInvalidSignature(t *testing.T) {
+	var (
+		n    = NewNode(ServerConfig{ListenAddr: ":3000"})
+		v, _ = signedVersion(t, n, crypto.GeneratePrivateKey(), ":4000")
+	)
+	// claims to own a key that did not sign the version
+	v.PublicKey = crypto.GeneratePrivateKey().Public().Bytes()
+	_, err := n.Handshake(context.Background(), v)
+	require.NotNil(t, err)
+	assert.Empty(t, n.peers)
+}
+
+func TestHandshakeAllowedPeers(t *testing.T) {
+	var (
+		allowedKey = crypto.GeneratePrivateKey()
+		n          = NewNode(ServerConfig{
+			ListenAddr:   ":3000",
+			AllowedPeers: []string{NodeIDFromPublicKey(allowedKey.Public().Bytes())},
+		})
+	)
+	v, _ := signedVersion(t, n, crypto.GeneratePrivateKey(), ":4000")
+	_, err := n.Handshake(context.Background(), v)
+	require.NotNil(t, err)
+
+	v, _ = signedVersion(t, n, allowedKey, ":6000")
+	_, err = n.Handshake(context.Background(), v)
+	require.Nil(t, err)
+	assert.Equal(t, []string{":6000"}, n.getPeerList())
+}
+
+func TestHandshakeOtherGenesis(t *testing.T) {
+	// same chain and genesis block, but another block time
+	genesis := DefaultGenesis()
+	genesis.BlockTime = util.Duration(time.Second)
+	n := NewNode(ServerConfig{ListenAddr: ":3000", Genesis: genesis})
+	block, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	defaultBlock, err := DefaultGenesis().Block()
+	require.Nil(t, err)
+	require.Equal(t, types.HashBlock(defaultBlock), types.HashBlock(block))
+
+	v, _ := signedVersion(t, n, crypto.GeneratePrivateKey(), ":4000")
+	_, err = n.Handshake(context.Background(), v)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Empty(t, n.peers)
+}
+
+func TestStartStop(t *testing.T) {
+	n := NewNode(ServerConfig{ListenAddr: freeAddr(t), PrivateKey: crypto.GeneratePrivateKey()})
+	done := make(chan error)
+	go func() { done <- n.Start(context.Background()) }()
+	require.Eventually(t, func() bool {
+		n.serverMu.Lock()
+		defer n.serverMu.Unlock()
+		return n.server != nil
+	}, time.Second, 10*time.Millisecond)
+
+	n.Stop()
+	select {
+	case err := <-done:
+		require.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+	// stopping again does nothing
+	n.Stop()
+}
+
+func TestStopOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := NewNode(ServerConfig{ListenAddr: freeAddr(t), PrivateKey: crypto.GeneratePrivateKey()})
+	done := make(chan error)
+	go func() { done <- n.Start(ctx) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		require.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after the context was canceled")
+	}
+	assert.NotNil(t, n.ctx.Err())
+}
+
+func TestStopClosesPeers(t *testing.T) {
+	server, addr := startTestNode(t, ServerConfig{})
+	startTestNode(t, ServerConfig{BootstrapNodes: []string{addr}})
+	require.Eventually(t, func() bool {
+		return len(server.getPeerList()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	server.peerLock.RLock()
+	var conn *grpc.ClientConn
+	for _, peer := range server.peers {
+		conn = peer.conn
+	}
+	server.peerLock.RUnlock()
+
+	server.Stop()
+	assert.Empty(t, server.getPeerList())
+	assert.Equal(t, connectivity.Shutdown, conn.GetState())
+	// nothing runs in background anymore
+	assert.False(t, server.spawn(func() {}))
+}
@@ -0,0 +1,209 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Penalties applied to the score of a peer when it misbehaves
+const (
+	PenaltyMalformedTx      = 20
+	PenaltyInvalidSignature = 50
+	PenaltyTxFlood          = 5
+	PenaltyInvalidBlock     = 100
+
+	BanThreshold       = -100 // a peer is banned when its score reaches this value
+	DefaultBanDuration = 24 * time.Hour
+
+	txFloodWindow = time.Second
+	txFloodLimit  = 100 // transactions a single peer can send in a flood window without being penalized
+)
+
+type peerState struct {
+	score       int
+	windowStart time.Time
+	txCount     int
+}
+
+/*
+Keeps the score of each peer. Every peer starts with score 0, which is decremented for each validation failure.
+
+When the score reaches BanThreshold, the peer is banned for the ban duration and its score is reset
+*/
+type PeerScores struct {
+	lock        sync.Mutex
+	peers       map[string]*peerState
+	bans        *BanList
+	banDuration time.Duration
+}
+
+func NewPeerScores(bans *BanList, banDuration time.Duration) *PeerScores {
+	if banDuration <= 0 {
+		banDuration = DefaultBanDuration
+	}
+	return &PeerScores{
+		peers:       make(map[string]*peerState),
+		bans:        bans,
+		banDuration: banDuration,
+	}
+}
+
+func (s *PeerScores) state(key string) *peerState {
+	st, ok := s.peers[key]
+	if !ok {
+		st = &peerState{}
+		s.peers[key] = st
+	}
+	return st
+}
+
+// Decrements the score of the peer, returning true if the peer got banned
+func (s *PeerScores) Penalize(key string, penalty int) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.state(key)
+	st.score -= penalty
+	if st.score > BanThreshold {
+		return false, nil
+	}
+	delete(s.peers, key)
+	return true, s.bans.Ban(key, time.Now().Add(s.banDuration))
+}
+
+// Counts a transaction received from the peer, returning true if the peer is flooding the node
+func (s *PeerScores) CountTx(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.state(key)
+	now := time.Now()
+	if now.Sub(st.windowStart) > txFloodWindow {
+		st.windowStart = now
+		st.txCount = 0
+	}
+	st.txCount++
+	return st.txCount > txFloodLimit
+}
+
+func (s *PeerScores) Score(key string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if st, ok := s.peers[key]; ok {
+		return st.score
+	}
+	return 0
+}
+
+// Resets the score of the peer, used when it is unbanned
+func (s *PeerScores) Reset(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.peers, key)
+}
+
+/*
+List of banned peers (by node ID or host) with the time their ban expires.
+
+When created with a path, every change is persisted to the file, so bans survive restarts
+*/
+type BanList struct {
+	lock sync.RWMutex
+	path string
+	bans map[string]time.Time
+}
+
+// Loads the ban list from the file in path. An empty path keeps the list only in memory
+func LoadBanList(path string) (*BanList, error) {
+	list := &BanList{
+		path: path,
+		bans: make(map[string]time.Time),
+	}
+	if path == "" {
+		return list, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &list.bans); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (l *BanList) Ban(key string, until time.Time) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.bans[key] = until
+	return l.save()
+}
+
+// Removes the ban of the peer, returning false if it was not banned
+func (l *BanList) Unban(key string) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.bans[key]; !ok {
+		return false, nil
+	}
+	delete(l.bans, key)
+	return true, l.save()
+}
+
+func (l *BanList) IsBanned(key string) bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	until, ok := l.bans[key]
+	return ok && time.Now().Before(until)
+}
+
+// Returns the keys of the peers with an active ban, sorted, and the time each ban expires
+func (l *BanList) List() ([]string, map[string]time.Time) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	now := time.Now()
+	keys := []string{}
+	bans := make(map[string]time.Time)
+	for key, until := range l.bans {
+		if now.Before(until) {
+			keys = append(keys, key)
+			bans[key] = until
+		}
+	}
+	sort.Strings(keys)
+	return keys, bans
+}
+
+// Writes the ban list to its file
+func (l *BanList) Flush() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.save()
+}
+
+// Writes the active bans to the file (must be called with the lock held)
+func (l *BanList) save() error {
+	now := time.Now()
+	for key, until := range l.bans { // expired bans are not persisted
+		if now.After(until) {
+			delete(l.bans, key)
+		}
+	}
+	if l.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(l.bans, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
@@ -0,0 +1,212 @@
+package node
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// Returns a context of a call made from the given remote address
+func peerContext(addr string) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+}
+
+func signedTransaction() *proto.Transaction {
+	privKey := crypto.GeneratePrivateKey()
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   util.RandomHash(),
+				PrevOutIndex: 0,
+				PublicKey:    privKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  99,
+				Address: privKey.Public().Address().Bytes(),
+			},
+		},
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+	return tx
+}
+
+func TestPeerScoresBan(t *testing.T) {
+	bans, err := LoadBanList("")
+	require.Nil(t, err)
+	scores := NewPeerScores(bans, time.Hour)
+
+	banned, err := scores.Penalize("10.0.0.1", PenaltyInvalidSignature)
+	require.Nil(t, err)
+	assert.False(t, banned)
+	assert.Equal(t, -PenaltyInvalidSignature, scores.Score("10.0.0.1"))
+
+	banned, err = scores.Penalize("10.0.0.1", PenaltyInvalidSignature)
+	require.Nil(t, err)
+	assert.True(t, banned)
+	assert.True(t, bans.IsBanned("10.0.0.1"))
+	assert.False(t, bans.IsBanned("10.0.0.2"))
+	// score starts again after the ban
+	assert.Equal(t, 0, scores.Score("10.0.0.1"))
+}
+
+func TestBanListExpiry(t *testing.T) {
+	bans, err := LoadBanList("")
+	require.Nil(t, err)
+	require.Nil(t, bans.Ban("10.0.0.1", time.Now().Add(-time.Second)))
+	assert.False(t, bans.IsBanned("10.0.0.1"))
+	keys, _ := bans.List()
+	assert.Empty(t, keys)
+}
+
+func TestBanListPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+	bans, err := LoadBanList(path)
+	require.Nil(t, err)
+	require.Nil(t, bans.Ban("10.0.0.1", time.Now().Add(time.Hour)))
+	require.Nil(t, bans.Ban("10.0.0.2", time.Now().Add(time.Hour)))
+
+	loaded, err := LoadBanList(path)
+	require.Nil(t, err)
+	assert.True(t, loaded.IsBanned("10.0.0.1"))
+	assert.True(t, loaded.IsBanned("10.0.0.2"))
+
+	ok, err := loaded.Unban("10.0.0.1")
+	require.Nil(t, err)
+	assert.True(t, ok)
+	loaded, err = LoadBanList(path)
+	require.Nil(t, err)
+	assert.False(t, loaded.IsBanned("10.0.0.1"))
+	assert.True(t, loaded.IsBanned("10.0.0.2"))
+}
+
+func TestHandleTransactionInvalidSignatureBansPeer(t *testing.T) {
+	var (
+		n   = NewNode(ServerConfig{ListenAddr: ":3000"})
+		ctx = peerContext("10.0.0.1:5000")
+	)
+	toAddress := crypto.GeneratePrivateKey().Public().Address().Bytes()
+	ack, err := n.HandleTransaction(ctx, genesisTransaction(t, n.chain, toAddress, 100))
+	require.Nil(t, err)
+	assert.True(t, ack.Accepted)
+	assert.Equal(t, 1, n.mempool.Len())
+
+	for i := 0; i < 2; i++ {
+		tx := signedTransaction()
+		tx.Outputs[0].Amount = 1000 // changes the transaction after it was signed
+		ack, err := n.HandleTransaction(ctx, tx)
+		require.Nil(t, err)
+		assert.False(t, ack.Accepted)
+	}
+	assert.True(t, n.bans.IsBanned("10.0.0.1"))
+	assert.Equal(t, 1, n.mempool.Len())
+
+	// banned peers cannot call the node service, even from another port
+	info := &grpc.UnaryServerInfo{FullMethod: "/Node/HandleTransaction"}
+	_, err = n.banInterceptor(peerContext("10.0.0.1:6000"), signedTransaction(), info, func(ctx context.Context, req any) (any, error) {
+		return n.HandleTransaction(ctx, req.(*proto.Transaction))
+	})
+	require.NotNil(t, err)
+}
+
+func TestHandleTransactionMalformed(t *testing.T) {
+	var (
+		n   = NewNode(ServerConfig{ListenAddr: ":3000"})
+		ctx = peerContext("10.0.0.1:5000")
+		tx  = signedTransaction()
+	)
+	tx.Inputs[0].Signature = nil
+	ack, err := n.HandleTransaction(ctx, tx)
+	require.Nil(t, err)
+	assert.False(t, ack.Accepted)
+	assert.NotEmpty(t, ack.Error)
+	assert.Equal(t, -PenaltyMalformedTx, n.scores.Score("10.0.0.1"))
+}
+
+func TestLocalAndAllowedPeersNotPenalized(t *testing.T) {
+	var (
+		allowed = NodeIDFromPublicKey(crypto.GeneratePrivateKey().Public().Bytes())
+		n       = NewNode(ServerConfig{ListenAddr: ":3000", AllowedPeers: []string{allowed}})
+	)
+	for i := 0; i < 2; i++ {
+		tx := signedTransaction()
+		tx.Outputs[0].Amount = 1000 // changes the transaction after it was signed
+		ack, err := n.HandleTransaction(peerContext("127.0.0.1:5000"), tx)
+		require.Nil(t, err)
+		assert.False(t, ack.Accepted)
+	}
+	assert.False(t, n.bans.IsBanned("127.0.0.1"))
+	assert.Equal(t, 0, n.scores.Score("127.0.0.1"))
+
+	n.penalize(allowed, PenaltyInvalidBlock, "invalid block")
+	assert.False(t, n.bans.IsBanned(allowed))
+	n.penalize("10.0.0.1", PenaltyInvalidBlock, "invalid block")
+	assert.True(t, n.bans.IsBanned("10.0.0.1"))
+}
+
+func TestHandleKnownBlock(t *testing.T) {
+	var (
+		n     = NewNode(ServerConfig{ListenAddr: ":3000"})
+		ctx   = peerContext("10.0.0.1:5000")
+		block = blockOn(tip(t, n.chain))
+	)
+	ack, err := n.HandleBlock(ctx, block)
+	require.Nil(t, err)
+	assert.True(t, ack.Accepted)
+	assert.Equal(t, 1, n.chain.Height())
+
+	// the same block again is ignored
+	ack, err = n.HandleBlock(ctx, block)
+	require.Nil(t, err)
+	assert.True(t, ack.Accepted)
+	assert.Equal(t, 1, n.chain.Height())
+	assert.Equal(t, 0, n.scores.Score("10.0.0.1"))
+}
+
+func TestHandleBlockInvalidSignatureBansPeer(t *testing.T) {
+	var (
+		n     = NewNode(ServerConfig{ListenAddr: ":3000"})
+		ctx   = peerContext("10.0.0.1:5000")
+		block = blockOn(tip(t, n.chain))
+	)
+	block.Header.Timestamp++ // changes the block after it was signed
+	ack, err := n.HandleBlock(ctx, block)
+	require.Nil(t, err)
+	assert.False(t, ack.Accepted)
+	assert.Equal(t, 0, n.chain.Height())
+	assert.True(t, n.bans.IsBanned("10.0.0.1"))
+}
+
+func TestAdminUnbanPeer(t *testing.T) {
+	var (
+		n     = NewNode(ServerConfig{ListenAddr: ":3000"})
+		admin = NewAdminServer(n)
+	)
+	require.Nil(t, n.bans.Ban("10.0.0.1", time.Now().Add(time.Hour)))
+
+	list, err := admin.ListBannedPeers(context.Background(), &proto.ListBannedPeersRequest{})
+	require.Nil(t, err)
+	require.Len(t, list.Peers, 1)
+	assert.Equal(t, "10.0.0.1", list.Peers[0].Peer)
+
+	_, err = admin.UnbanPeer(context.Background(), &proto.UnbanPeerRequest{Peer: "10.0.0.1"})
+	require.Nil(t, err)
+	assert.False(t, n.bans.IsBanned("10.0.0.1"))
+
+	_, err = admin.UnbanPeer(context.Background(), &proto.UnbanPeerRequest{Peer: "10.0.0.1"})
+	require.NotNil(t, err)
+}
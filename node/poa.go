@@ -0,0 +1,140 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+)
+
+const (
+	slotChecksPerBlock   = 10 // times per block time that a validator checks if it is the leader
+	minSlotCheckInterval = 10 * time.Millisecond
+	maxClockDriftBlocks  = 2 // a block may be ahead of the clock of the node by up to blockTime/maxClockDriftBlocks
+)
+
+/*
+Validators of a proof-of-authority chain, defined in the genesis and changed by transactions (see Chain.Validators).
+
+Each height has a round-robin schedule of leaders: the leader of round r of height h is validators[(h+r) % n].
+The round of a block comes from the time since its parent: the first block time after the parent is too early,
+the next one is round 0, and every further block time without a block moves to the next round,
+so a leader that is offline (missed its slot) is replaced by the next validator
+*/
+type ValidatorSet struct {
+	validators [][]byte // public keys, in the order of the genesis and then of joining
+	blockTime  time.Duration
+	stakes     map[string]string // output locked by each validator (validators of the genesis have no stake)
+}
+
+// Validator set of the genesis, or nil if the genesis has no validators (blocks signed by any key are accepted)
+func NewValidatorSet(g *Genesis) (*ValidatorSet, error) {
+	if len(g.Validators) == 0 {
+		return nil, nil
+	}
+	vs := &ValidatorSet{blockTime: time.Duration(g.BlockTime), stakes: make(map[string]string)}
+	for _, validator := range g.Validators {
+		pubKey, err := hex.DecodeString(validator)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validator public key %q", validator)
+		}
+		if vs.Contains(pubKey) {
+			return nil, fmt.Errorf("validator %s is repeated", validator)
+		}
+		vs.validators = append(vs.validators, pubKey)
+	}
+	return vs, nil
+}
+
+func (vs *ValidatorSet) Len() int {
+	return len(vs.validators)
+}
+
+func (vs *ValidatorSet) Contains(pubKey []byte) bool {
+	return vs.indexOf(pubKey) >= 0
+}
+
+func (vs *ValidatorSet) Leader(height, round int) []byte {
+	return vs.validators[(height+round)%len(vs.validators)]
+}
+
+// Round of a block created at timestamp on top of a block created at parentTimestamp (in nanoseconds)
+func (vs *ValidatorSet) Round(parentTimestamp, timestamp int64) (int, error) {
+	elapsed := time.Duration(timestamp - parentTimestamp)
+	if elapsed < vs.blockTime {
+		return 0, fmt.Errorf("block created %s after its parent, before the block time (%s)", elapsed, vs.blockTime)
+	}
+	return int((elapsed - vs.blockTime) / vs.blockTime), nil
+}
+
+// Verifies that the block of height, created at timestamp, is signed by the leader of its round
+func (vs *ValidatorSet) CheckLeader(height int, parentTimestamp, timestamp int64, pubKey []byte) error {
+	round, err := vs.Round(parentTimestamp, timestamp)
+	if err != nil {
+		return err
+	}
+	if leader := vs.Leader(height, round); !bytes.Equal(leader, pubKey) {
+		return fmt.Errorf("block %d (round %d) signed by %x, the leader is %x", height, round, pubKey, leader)
+	}
+	return nil
+}
+
+/*
+Consensus of a chain whose blocks are created by the validators, taking turns.
+The leaders of a height are chosen among the validators active at the height in the chain
+*/
+type ProofOfAuthority struct {
+	validators *ValidatorSet // validators of the genesis
+	staking    bool          // the validators change with transactions
+}
+
+// Validators of the genesis
+func (poa *ProofOfAuthority) Validators() *ValidatorSet {
+	return poa.validators
+}
+
+// With staking any key may join the set, so it is checked for each block (Prepare)
+func (poa *ProofOfAuthority) Authorized(pubKey []byte) bool {
+	return poa.staking || poa.validators.Contains(pubKey)
+}
+
+// The validators check several times per block time (of the genesis, the same for all of them) if they are the leader
+func (poa *ProofOfAuthority) Interval() time.Duration {
+	return max(poa.validators.blockTime/slotChecksPerBlock, minSlotCheckInterval)
+}
+
+func (poa *ProofOfAuthority) Prepare(chain ChainReader, parent, header *proto.Header, pubKey []byte) error {
+	if err := poa.VerifyHeader(chain, parent, header, pubKey); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotReady, err)
+	}
+	return nil
+}
+
+func (poa *ProofOfAuthority) Seal(ctx context.Context, b *proto.Block, key *crypto.PrivateKey) error {
+	types.SignBlock(key, b)
+	return nil
+}
+
+/*
+Verifies that the block is signed by the leader of its round among the validators active at its height in the chain.
+The round comes from the timestamp chosen by the leader, so blocks ahead of the clock of the node by more than
+a fraction of the block time are rejected: otherwise a validator could stamp the slots of later rounds it leads
+and take over the chain.
+Blocks of forks are verified against the validators of the chain, and again against the ones of the fork if it is chosen
+*/
+func (poa *ProofOfAuthority) VerifyHeader(chain ChainReader, parent, header *proto.Header, pubKey []byte) error {
+	if ahead := time.Duration(header.Timestamp - time.Now().UnixNano()); ahead > poa.validators.blockTime/maxClockDriftBlocks {
+		return fmt.Errorf("%w: block timestamp %s ahead of the clock of the node", ErrFutureBlock, ahead)
+	}
+	height := int(parent.Height) + 1
+	return chain.Validators(height).CheckLeader(height, parent.Timestamp, header.Timestamp, pubKey)
+}
+
+func (poa *ProofOfAuthority) ChooseFork(current, candidate []*proto.Header) bool {
+	return longestChain(current, candidate)
+}
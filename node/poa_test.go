@@ -0,0 +1,124 @@
+package node
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorSetSchedule(t *testing.T) {
+	keys := []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+	vs, err := NewValidatorSet(testGenesis(withValidators(keys...)))
+	require.Nil(t, err)
+	assert.Equal(t, 3, vs.Len())
+	assert.True(t, vs.Contains(keys[1].Public().Bytes()))
+	assert.False(t, vs.Contains(crypto.GeneratePrivateKey().Public().Bytes()))
+
+	// round robin by height, moving to the next validator in each round
+	assert.Equal(t, keys[1].Public().Bytes(), vs.Leader(1, 0))
+	assert.Equal(t, keys[2].Public().Bytes(), vs.Leader(2, 0))
+	assert.Equal(t, keys[0].Public().Bytes(), vs.Leader(2, 1))
+
+	second := int64(time.Second)
+	_, err = vs.Round(0, second-1)
+	assert.NotNil(t, err)
+	for elapsed, round := range map[int64]int{second: 0, 2*second - 1: 0, 2 * second: 1, 5 * second: 4} {
+		r, err := vs.Round(0, elapsed)
+		require.Nil(t, err)
+		assert.Equal(t, round, r, elapsed)
+	}
+	assert.Nil(t, vs.CheckLeader(1, 0, second, keys[1].Public().Bytes()))
+	assert.NotNil(t, vs.CheckLeader(1, 0, second, keys[2].Public().Bytes()))
+	assert.Nil(t, vs.CheckLeader(1, 0, 2*second, keys[2].Public().Bytes()))
+
+	genesis := testGenesis(withValidators(keys[0], keys[0]))
+	_, err = NewValidatorSet(genesis)
+	assert.ErrorContains(t, err, "repeated")
+	vs, err = NewValidatorSet(testGenesis(withValidators()))
+	assert.Nil(t, err)
+	assert.Nil(t, vs)
+}
+
+func TestChainRejectsBlocksOfOtherLeaders(t *testing.T) {
+	var (
+		first   = crypto.GeneratePrivateKey()
+		second  = crypto.GeneratePrivateKey()
+		genesis = testGenesis(withValidators(first, second))
+	)
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	start := genesis.Timestamp
+
+	// height 1 belongs to the second validator
+	_, err = chain.AddBlock(blockOn(tip(t, chain), signedBy(first), at(start.Add(time.Second))))
+	assert.ErrorContains(t, err, "the leader is")
+	_, err = chain.AddBlock(blockOn(tip(t, chain), signedBy(crypto.GeneratePrivateKey()), at(start.Add(time.Second))))
+	assert.ErrorContains(t, err, "the leader is")
+	_, err = chain.AddBlock(blockOn(tip(t, chain), signedBy(second), at(start.Add(time.Second/2))))
+	assert.ErrorContains(t, err, "before the block time")
+	_, err = chain.AddBlock(blockOn(tip(t, chain), signedBy(second), at(start.Add(time.Second))))
+	require.Nil(t, err)
+
+	// the second validator takes the slot of the first one after it is missed
+	_, err = chain.AddBlock(blockOn(tip(t, chain), signedBy(second), at(start.Add(2*time.Second))))
+	assert.NotNil(t, err)
+	_, err = chain.AddBlock(blockOn(tip(t, chain), signedBy(second), at(start.Add(3*time.Second))))
+	require.Nil(t, err)
+	assert.Equal(t, 2, chain.Height())
+}
+
+func TestChainRejectsFutureRounds(t *testing.T) {
+	var (
+		first   = crypto.GeneratePrivateKey()
+		second  = crypto.GeneratePrivateKey()
+		genesis = testGenesis(withValidators(first, second))
+	)
+	genesis.Timestamp = time.Now().UTC()
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+
+	// height 1 belongs to the second validator: the first one leads round 1, which starts in two seconds
+	b := blockOn(tip(t, chain), signedBy(first), at(genesis.Timestamp.Add(2*time.Second)))
+	_, err = chain.AddBlock(b)
+	assert.ErrorContains(t, err, "ahead of the clock")
+	assert.ErrorIs(t, err, ErrFutureBlock)
+	parent, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	assert.ErrorIs(t, chain.Consensus().Prepare(chain, parent.Header, b.Header, first.Public().Bytes()), ErrNotReady)
+}
+
+func TestProofOfAuthorityNodes(t *testing.T) {
+	var (
+		first   = crypto.GeneratePrivateKey()
+		second  = crypto.GeneratePrivateKey()
+		genesis = testGenesis(withValidators(first, second), withBlockTime(100*time.Millisecond))
+	)
+	genesis.Timestamp = time.Now().UTC() // the nodes start together, they do not sync past blocks
+	a, addrA := startTestNode(t, ServerConfig{PrivateKey: first, Genesis: genesis})
+	b, _ := startTestNode(t, ServerConfig{PrivateKey: second, Genesis: genesis, BootstrapNodes: []string{addrA}})
+	outsider, _ := startTestNode(t, ServerConfig{PrivateKey: crypto.GeneratePrivateKey(), Genesis: genesis, BootstrapNodes: []string{addrA}})
+
+	// the validators take turns, and the node whose key is not a validator only follows them
+	require.Eventually(t, func() bool {
+		return a.chain.Height() >= 4 && b.chain.Height() >= 4 && outsider.chain.Height() >= 4
+	}, 5*time.Second, 10*time.Millisecond)
+	signers := map[string]bool{}
+	for h := 1; h <= 4; h++ {
+		block, err := outsider.chain.GetBlockByHeight(h)
+		require.Nil(t, err)
+		signers[string(block.PublicKey)] = true
+		assert.False(t, bytes.Equal(outsider.PrivateKey.Public().Bytes(), block.PublicKey))
+	}
+	assert.Len(t, signers, 2)
+
+	// when a validator goes offline, the other one takes its slots
+	b.Stop()
+	height := a.chain.Height()
+	require.Eventually(t, func() bool {
+		return a.chain.Height() >= height+3
+	}, 5*time.Second, 10*time.Millisecond)
+}
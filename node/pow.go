@@ -0,0 +1,182 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	DefaultPowBits          = 0x1f00ffff // about 65 thousand hashes per block
+	DefaultRetargetInterval = 10
+	miningInterval          = 10 * time.Millisecond // the miner starts the next block right after the last one
+	nonceCheckInterval      = 1 << 12               // nonces tried by a mining goroutine between the checks of the context
+)
+
+/*
+Consensus of a chain whose blocks are mined: any key creates a block by finding a nonce whose header hash
+is lower than the target of the bits of the block, and the branch with more work wins.
+
+Every retarget interval the target is adjusted by the time the last blocks took compared to the block time
+(at most 4 times easier or harder), so the blocks keep the block time as the hash rate of the network changes
+*/
+type ProofOfWork struct {
+	bits             uint32 // bits of the genesis, the easiest target
+	retargetInterval int
+	blockTime        time.Duration
+	workers          int // goroutines searching the nonce
+}
+
+func NewProofOfWork(g *Genesis) *ProofOfWork {
+	return &ProofOfWork{
+		bits:             g.ProofOfWork.Bits,
+		retargetInterval: g.ProofOfWork.RetargetInterval,
+		blockTime:        time.Duration(g.BlockTime),
+		workers:          runtime.NumCPU(),
+	}
+}
+
+func (pow *ProofOfWork) Authorized(pubKey []byte) bool {
+	return true
+}
+
+func (pow *ProofOfWork) Interval() time.Duration {
+	return miningInterval
+}
+
+func (pow *ProofOfWork) Prepare(chain ChainReader, parent, header *proto.Header, pubKey []byte) error {
+	bits, err := pow.nextBits(chain, parent)
+	if err != nil {
+		return err
+	}
+	header.Bits = bits
+	return nil
+}
+
+// Mines the block and signs it. The signature does not change the hash of the block, so anyone can sign a mined block
+func (pow *ProofOfWork) Seal(ctx context.Context, b *proto.Block, key *crypto.PrivateKey) error {
+	types.SetRootHash(b) // the transactions are committed before mining
+	if err := pow.mine(ctx, b.Header); err != nil {
+		return err
+	}
+	types.SignBlock(key, b)
+	return nil
+}
+
+func (pow *ProofOfWork) VerifyHeader(chain ChainReader, parent, header *proto.Header, pubKey []byte) error {
+	bits, err := pow.nextBits(chain, parent)
+	if err != nil {
+		return err
+	}
+	if header.Bits != bits {
+		return fmt.Errorf("block has bits %08x, expected %08x", header.Bits, bits)
+	}
+	if !types.CheckProofOfWork(header) {
+		return fmt.Errorf("block hash is higher than the target of its bits")
+	}
+	return nil
+}
+
+// Chooses the branch with more cumulative work, keeping the current one on a tie
+func (pow *ProofOfWork) ChooseFork(current, candidate []*proto.Header) bool {
+	return chainWork(candidate).Cmp(chainWork(current)) > 0
+}
+
+func chainWork(headers []*proto.Header) *big.Int {
+	work := new(big.Int)
+	for _, header := range headers {
+		work.Add(work, types.Work(header.Bits))
+	}
+	return work
+}
+
+/*
+Bits of the block on top of the parent: the bits of the parent, except on the heights multiple of the retarget interval
+ 1. The time of the last interval is the time between the parent and the first block of the interval,
+    which are retargetInterval-1 blocks apart
+ 2. The time is limited to 4 times shorter or longer than the expected one (the block time of each of those blocks)
+ 3. The target is multiplied by the time over the expected one, limited to the target of the genesis
+*/
+func (pow *ProofOfWork) nextBits(chain ChainReader, parent *proto.Header) (uint32, error) {
+	height := int(parent.Height) + 1
+	if height%pow.retargetInterval != 0 {
+		return parent.Bits, nil
+	}
+	first := parent
+	for i := 1; i < pow.retargetInterval; i++ {
+		block, err := chain.GetBlockByHash(first.PrevHash)
+		if err != nil {
+			return 0, err
+		}
+		first = block.Header
+	}
+	var (
+		elapsed  = time.Duration(parent.Timestamp - first.Timestamp)
+		expected = time.Duration(pow.retargetInterval-1) * pow.blockTime
+	)
+	elapsed = min(max(elapsed, expected/4), expected*4)
+	target := types.CompactToTarget(parent.Bits)
+	target.Mul(target, big.NewInt(int64(elapsed)))
+	target.Div(target, big.NewInt(int64(expected)))
+	if limit := types.CompactToTarget(pow.bits); target.Cmp(limit) > 0 {
+		target = limit
+	}
+	return types.TargetToCompact(target), nil
+}
+
+/*
+Searches a nonce that makes the hash of the header lower than its target, in several goroutines.
+Each goroutine hashes its own copy of the header, trying the nonces worker, worker+workers, worker+2*workers...
+The search stops when one of them finds a nonce or when the context is canceled
+*/
+func (pow *ProofOfWork) mine(ctx context.Context, header *proto.Header) error {
+	var (
+		mineCtx, cancel = context.WithCancel(ctx)
+		target          = types.CompactToTarget(header.Bits)
+		found           = make(chan uint64, 1)
+		wg              sync.WaitGroup
+		step            = uint64(pow.workers)
+	)
+	defer cancel()
+	for worker := uint64(0); worker < step; worker++ {
+		wg.Add(1)
+		go func(nonce uint64) {
+			defer wg.Done()
+			var (
+				h    = pb.Clone(header).(*proto.Header)
+				hash = new(big.Int)
+			)
+			for tries := 1; ; tries++ {
+				if tries%nonceCheckInterval == 0 && mineCtx.Err() != nil {
+					return
+				}
+				h.Nonce = nonce
+				if hash.SetBytes(types.HashHeader(h)).Cmp(target) <= 0 {
+					select {
+					case found <- nonce:
+					default: // another goroutine found one first
+					}
+					cancel()
+					return
+				}
+				nonce += step
+			}
+		}(worker)
+	}
+	wg.Wait()
+	select {
+	case nonce := <-found:
+		header.Nonce = nonce
+		return nil
+	default:
+		return ctx.Err()
+	}
+}
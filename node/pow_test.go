@@ -0,0 +1,147 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const easyBits = 0x207fffff // half of the hashes meet the target
+
+func TestProofOfWorkBlocks(t *testing.T) {
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), testGenesis(withProofOfWork(DefaultRetargetInterval)))
+	require.Nil(t, err)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	b := blockOn(tip(t, chain), at(time.Unix(0, genesis.Header.Timestamp+int64(time.Second))), sealedFor(t, chain))
+	assert.True(t, types.CheckProofOfWork(b.Header))
+	assert.Equal(t, uint32(easyBits), b.Header.Bits)
+	_, err = chain.AddBlock(b)
+	require.Nil(t, err)
+
+	// blocks with other bits or whose hash does not meet the target are rejected
+	b = blockOn(tip(t, chain), at(time.Unix(0, genesis.Header.Timestamp+2*int64(time.Second))), sealedFor(t, chain))
+	for types.CheckProofOfWork(b.Header) {
+		b.Header.Nonce++
+	}
+	types.SignBlock(crypto.GeneratePrivateKey(), b)
+	_, err = chain.AddBlock(b)
+	assert.ErrorContains(t, err, "higher than the target")
+	b.Header.Bits = 0x1f00ffff
+	types.SignBlock(crypto.GeneratePrivateKey(), b)
+	_, err = chain.AddBlock(b)
+	assert.ErrorContains(t, err, "expected 207fffff")
+}
+
+func TestProofOfWorkRetarget(t *testing.T) {
+	var (
+		genesis = testGenesis(withProofOfWork(4))
+		step    = int64(genesis.BlockTime) / 2
+	)
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	pow := chain.Consensus().(*ProofOfWork)
+
+	// blocks twice faster than the block time make the target harder
+	timestamp := genesis.Timestamp.UnixNano()
+	for chain.Height() < 3 {
+		timestamp += step
+		_, err = chain.AddBlock(blockOn(tip(t, chain), at(time.Unix(0, timestamp)), sealedFor(t, chain)))
+		require.Nil(t, err)
+	}
+	parent, err := chain.GetBlockByHeight(3)
+	require.Nil(t, err)
+	bits, err := pow.nextBits(chain, parent.Header)
+	require.Nil(t, err)
+	assert.Equal(t, -1, types.CompactToTarget(bits).Cmp(types.CompactToTarget(easyBits)))
+	timestamp += step
+	_, err = chain.AddBlock(blockOn(tip(t, chain), at(time.Unix(0, timestamp)), sealedFor(t, chain)))
+	require.Nil(t, err)
+	last, err := chain.GetBlockByHeight(4)
+	require.Nil(t, err)
+	assert.Equal(t, bits, last.Header.Bits)
+
+	// slow blocks make it easier, up to the target of the genesis
+	for chain.Height() < 7 {
+		timestamp += 10 * int64(genesis.BlockTime)
+		_, err = chain.AddBlock(blockOn(tip(t, chain), at(time.Unix(0, timestamp)), sealedFor(t, chain)))
+		require.Nil(t, err)
+	}
+	parent, err = chain.GetBlockByHeight(7)
+	require.Nil(t, err)
+	bits, err = pow.nextBits(chain, parent.Header)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(easyBits), bits)
+}
+
+func TestProofOfWorkRetargetOnSchedule(t *testing.T) {
+	genesis := testGenesis(withProofOfWork(4))
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	pow := chain.Consensus().(*ProofOfWork)
+
+	// blocks exactly one block time apart keep the target
+	timestamp := genesis.Timestamp.UnixNano()
+	for chain.Height() < 3 {
+		timestamp += int64(genesis.BlockTime)
+		_, err = chain.AddBlock(blockOn(tip(t, chain), at(time.Unix(0, timestamp)), sealedFor(t, chain)))
+		require.Nil(t, err)
+	}
+	parent, err := chain.GetBlockByHeight(3)
+	require.Nil(t, err)
+	bits, err := pow.nextBits(chain, parent.Header)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(easyBits), bits)
+}
+
+func TestProofOfWorkForkChoice(t *testing.T) {
+	var (
+		pow  = NewProofOfWork(testGenesis(withProofOfWork(DefaultRetargetInterval)))
+		easy = &proto.Header{Bits: 0x1f00ffff}
+		hard = &proto.Header{Bits: 0x1e00ffff} // 256 times the work
+	)
+	assert.True(t, pow.ChooseFork([]*proto.Header{easy, easy}, []*proto.Header{hard}))
+	assert.False(t, pow.ChooseFork([]*proto.Header{hard}, []*proto.Header{easy, easy}))
+	assert.False(t, pow.ChooseFork([]*proto.Header{hard}, []*proto.Header{hard}))
+}
+
+func TestMineCanceled(t *testing.T) {
+	var (
+		pow         = NewProofOfWork(testGenesis(withProofOfWork(DefaultRetargetInterval)))
+		ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	)
+	defer cancel()
+	assert.ErrorIs(t, pow.mine(ctx, &proto.Header{Bits: 0x03000001}), context.DeadlineExceeded)
+}
+
+func TestProofOfWorkNodes(t *testing.T) {
+	genesis := testGenesis(withProofOfWork(DefaultRetargetInterval))
+	genesis.Timestamp = time.Now().UTC()
+	follower, addr := startTestNode(t, ServerConfig{Genesis: genesis})
+
+	// the miner starts paused until it is connected, as the nodes do not sync the blocks they missed
+	miner := NewNode(ServerConfig{ListenAddr: freeAddr(t), PrivateKey: crypto.GeneratePrivateKey(), Genesis: genesis, BootstrapNodes: []string{addr}})
+	miner.paused.Store(true)
+	go miner.Start(context.Background())
+	t.Cleanup(miner.Stop)
+	require.Eventually(t, func() bool {
+		return len(miner.getPeerList()) > 0
+	}, time.Second, 10*time.Millisecond)
+	miner.paused.Store(false)
+
+	require.Eventually(t, func() bool {
+		return follower.chain.Height() >= 5
+	}, 5*time.Second, 10*time.Millisecond)
+	for h := 1; h <= 5; h++ {
+		block, err := follower.chain.GetBlockByHeight(h)
+		require.Nil(t, err)
+		assert.True(t, types.CheckProofOfWork(block.Header))
+	}
+}
@@ -0,0 +1,204 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC service used by clients to read the chain and follow their transactions
+type QueryServer struct {
+	chain    *Chain
+	mempool  *Mempool
+	rejected *RejectedTxs
+	events   *EventBus
+
+	proto.UnimplementedQueryServer
+}
+
+func NewQueryServer(chain *Chain, mempool *Mempool, rejected *RejectedTxs, events *EventBus) *QueryServer {
+	return &QueryServer{
+		chain:    chain,
+		mempool:  mempool,
+		rejected: rejected,
+		events:   events,
+	}
+}
+
+func (s *QueryServer) GetBlockByHeight(ctx context.Context, req *proto.GetBlockByHeightRequest) (*proto.Block, error) {
+	block, err := s.chain.GetBlockByHeight(int(req.Height))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return block, nil
+}
+
+func (s *QueryServer) GetBlockByHash(ctx context.Context, req *proto.GetBlockByHashRequest) (*proto.Block, error) {
+	block, err := s.chain.GetBlockByHash(req.Hash)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return block, nil
+}
+
+func (s *QueryServer) GetTransaction(ctx context.Context, req *proto.GetTransactionRequest) (*proto.Transaction, error) {
+	tx, err := s.chain.GetTransaction(req.Hash)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return tx, nil
+}
+
+func (s *QueryServer) GetBalance(ctx context.Context, req *proto.GetBalanceRequest) (*proto.Balance, error) {
+	if len(req.Address) != crypto.AddressLen {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid address length (%d)", len(req.Address))
+	}
+	balance, err := s.chain.GetBalance(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.Balance{Address: req.Address, Amount: balance}, nil
+}
+
+func (s *QueryServer) ListUTXOs(ctx context.Context, req *proto.ListUTXOsRequest) (*proto.UTXOList, error) {
+	if len(req.Address) != crypto.AddressLen {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid address length (%d)", len(req.Address))
+	}
+	utxos, err := s.chain.GetUTXOs(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	list := &proto.UTXOList{}
+	for _, utxo := range utxos {
+		hash, err := hex.DecodeString(utxo.Hash)
+		if err != nil {
+			return nil, err
+		}
+		list.Utxos = append(list.Utxos, &proto.UTXO{
+			TxHash:   hash,
+			OutIndex: uint32(utxo.OutIndex),
+			Amount:   utxo.Amount,
+			Address:  utxo.Address,
+		})
+	}
+	return list, nil
+}
+
+/*
+Returns the status of the transaction:
+  - confirmed: added to a block, with the number of blocks on top of it (including its own block)
+  - pending: waiting in the mempool
+  - rejected: not valid, with the reason of the rejection
+  - unknown: never received by the node (or rejected long ago)
+*/
+func (s *QueryServer) GetTransactionStatus(ctx context.Context, req *proto.GetTransactionStatusRequest) (*proto.TransactionStatus, error) {
+	status := &proto.TransactionStatus{
+		Hash:   req.Hash,
+		Status: proto.TxStatus_TX_STATUS_UNKNOWN,
+	}
+	hash := hex.EncodeToString(req.Hash)
+	if location, ok := s.chain.GetTransactionLocation(req.Hash); ok {
+		status.Status = proto.TxStatus_TX_STATUS_CONFIRMED
+		status.BlockHash = location.BlockHash
+		status.BlockHeight = int32(location.Height)
+		status.Confirmations = int32(s.chain.Height() - location.Height + 1)
+	} else if s.mempool.HasHash(hash) {
+		status.Status = proto.TxStatus_TX_STATUS_PENDING
+	} else if reason, ok := s.rejected.Get(hash); ok {
+		status.Status = proto.TxStatus_TX_STATUS_REJECTED
+		status.Error = reason
+	}
+	return status, nil
+}
+
+// Streams the blocks added to the chain
+func (s *QueryServer) SubscribeBlocks(req *proto.SubscribeBlocksRequest, stream proto.Query_SubscribeBlocksServer) error {
+	sub := s.events.Subscribe(func(ev Event) bool {
+		return ev.Block != nil && !ev.Reverted
+	})
+	defer sub.Close()
+	return streamEvents(stream.Context(), sub, func(ev Event) error {
+		return stream.Send(ev.Block)
+	})
+}
+
+// Streams the transactions added to the mempool
+func (s *QueryServer) SubscribeMempool(req *proto.SubscribeMempoolRequest, stream proto.Query_SubscribeMempoolServer) error {
+	sub := s.events.Subscribe(func(ev Event) bool {
+		return ev.Tx != nil
+	})
+	defer sub.Close()
+	return streamEvents(stream.Context(), sub, func(ev Event) error {
+		return stream.Send(ev.Tx)
+	})
+}
+
+// Streams the transactions that send to or spend from the address, when they enter the mempool and when they are confirmed
+func (s *QueryServer) SubscribeAddress(req *proto.SubscribeAddressRequest, stream proto.Query_SubscribeAddressServer) error {
+	if len(req.Address) != crypto.AddressLen {
+		return status.Errorf(codes.InvalidArgument, "invalid address length (%d)", len(req.Address))
+	}
+	sub := s.events.Subscribe(func(ev Event) bool {
+		if ev.Tx != nil {
+			return txHasAddress(ev.Tx, req.Address)
+		}
+		if ev.Reverted { // the transactions still valid are sent again when they return to the mempool
+			return false
+		}
+		for _, tx := range ev.Block.Transactions {
+			if txHasAddress(tx, req.Address) {
+				return true
+			}
+		}
+		return false
+	})
+	defer sub.Close()
+	return streamEvents(stream.Context(), sub, func(ev Event) error {
+		if ev.Tx != nil {
+			return stream.Send(&proto.AddressEvent{Address: req.Address, Transaction: ev.Tx})
+		}
+		blockHash := types.HashBlock(ev.Block)
+		for _, tx := range ev.Block.Transactions {
+			if !txHasAddress(tx, req.Address) {
+				continue
+			}
+			err := stream.Send(&proto.AddressEvent{
+				Address:     req.Address,
+				Transaction: tx,
+				Confirmed:   true,
+				BlockHash:   blockHash,
+				BlockHeight: ev.Block.Header.Height,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Sends the events of the subscription until the client leaves or the subscription is closed by the bus
+func streamEvents(ctx context.Context, sub *Subscription, send func(Event) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-sub.Events():
+			if !ok {
+				if errors.Is(sub.Err(), ErrSlowSubscriber) {
+					return status.Error(codes.ResourceExhausted, sub.Err().Error())
+				}
+				return status.Error(codes.Unavailable, sub.Err().Error())
+			}
+			if err := send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
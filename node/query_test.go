@@ -0,0 +1,89 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Adds a block to the chain spending the genesis output: 100 to toAddress and 900 back to the genesis address
+func spendGenesis(t *testing.T, chain *Chain, toAddress []byte) *proto.Transaction {
+	tx := genesisTransaction(t, chain, toAddress, 100)
+	_, err := chain.AddBlock(blockOn(tip(t, chain), withTxs(tx)))
+	require.Nil(t, err)
+	return tx
+}
+
+func TestQueryBlocks(t *testing.T) {
+	var (
+		chain = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		query = NewQueryServer(chain, NewMemPool(), NewRejectedTxs(maxRejectedTxs), NewEventBus())
+		block = blockOn(tip(t, chain))
+	)
+	_, err := chain.AddBlock(block)
+	require.Nil(t, err)
+
+	byHeight, err := query.GetBlockByHeight(context.Background(), &proto.GetBlockByHeightRequest{Height: 1})
+	require.Nil(t, err)
+	assert.Equal(t, block, byHeight)
+
+	byHash, err := query.GetBlockByHash(context.Background(), &proto.GetBlockByHashRequest{Hash: types.HashBlock(block)})
+	require.Nil(t, err)
+	assert.Equal(t, block, byHash)
+
+	_, err = query.GetBlockByHeight(context.Background(), &proto.GetBlockByHeightRequest{Height: 2})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = query.GetBlockByHeight(context.Background(), &proto.GetBlockByHeightRequest{Height: -1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestQueryTransactionAndBalances(t *testing.T) {
+	var (
+		chain       = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		query       = NewQueryServer(chain, NewMemPool(), NewRejectedTxs(maxRejectedTxs), NewEventBus())
+		genesisKey  = crypto.NewPrivateKeyFromString(GenesisSeed).Public()
+		legacyAddr  = genesisKey.LegacyAddress().Bytes() // the allocation of the development chain
+		genesisAddr = genesisKey.Address().Bytes()       // receives the change
+		toAddress   = crypto.GeneratePrivateKey().Public().Address().Bytes()
+	)
+	balance, err := query.GetBalance(context.Background(), &proto.GetBalanceRequest{Address: legacyAddr})
+	require.Nil(t, err)
+	assert.Equal(t, int64(1000), balance.Amount)
+
+	tx := spendGenesis(t, chain, toAddress)
+	fetchedTx, err := query.GetTransaction(context.Background(), &proto.GetTransactionRequest{Hash: types.HashTransaction(tx)})
+	require.Nil(t, err)
+	assert.Equal(t, tx, fetchedTx)
+
+	balance, err = query.GetBalance(context.Background(), &proto.GetBalanceRequest{Address: genesisAddr})
+	require.Nil(t, err)
+	assert.Equal(t, int64(900), balance.Amount)
+	balance, err = query.GetBalance(context.Background(), &proto.GetBalanceRequest{Address: legacyAddr})
+	require.Nil(t, err)
+	assert.Zero(t, balance.Amount)
+
+	utxos, err := query.ListUTXOs(context.Background(), &proto.ListUTXOsRequest{Address: toAddress})
+	require.Nil(t, err)
+	require.Len(t, utxos.Utxos, 1)
+	assert.Equal(t, types.HashTransaction(tx), utxos.Utxos[0].TxHash)
+	assert.Equal(t, uint32(0), utxos.Utxos[0].OutIndex)
+	assert.Equal(t, int64(100), utxos.Utxos[0].Amount)
+
+	// the genesis output was spent, only the change is left
+	utxos, err = query.ListUTXOs(context.Background(), &proto.ListUTXOsRequest{Address: genesisAddr})
+	require.Nil(t, err)
+	require.Len(t, utxos.Utxos, 1)
+	assert.Equal(t, uint32(1), utxos.Utxos[0].OutIndex)
+
+	_, err = query.GetTransaction(context.Background(), &proto.GetTransactionRequest{Hash: make([]byte, 32)})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = query.GetBalance(context.Background(), &proto.GetBalanceRequest{Address: []byte{1, 2, 3}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
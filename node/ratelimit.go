@@ -0,0 +1,161 @@
+package node
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPeerRate    = 50   // requests per second allowed for a single peer
+	DefaultPeerBurst   = 100  // requests a single peer can send at once
+	DefaultGlobalRate  = 1000 // requests per second allowed for all the peers together
+	DefaultGlobalBurst = 2000
+
+	DefaultHandshakeRate  = 1 // handshakes per second allowed for a single caller
+	DefaultHandshakeBurst = 10
+
+	DefaultMaxMsgSize    = 1 << 20 // 1 MiB
+	DefaultMaxBroadcasts = 64      // broadcasts running at the same time
+
+	maxIdleBuckets = 10000 // peer buckets kept before idle ones are dropped
+)
+
+// Limits of the requests received by the node service. Zero values use the defaults
+type RateLimitConfig struct {
+	PeerRate    float64
+	PeerBurst   int
+	GlobalRate  float64
+	GlobalBurst int
+	// handshakes started by a single caller, limited apart from the other requests since each one keeps a challenge
+	HandshakeRate  float64
+	HandshakeBurst int
+}
+
+func (c RateLimitConfig) withDefaults() RateLimitConfig {
+	if c.PeerRate <= 0 {
+		c.PeerRate = DefaultPeerRate
+	}
+	if c.PeerBurst <= 0 {
+		c.PeerBurst = DefaultPeerBurst
+	}
+	if c.GlobalRate <= 0 {
+		c.GlobalRate = DefaultGlobalRate
+	}
+	if c.GlobalBurst <= 0 {
+		c.GlobalBurst = DefaultGlobalBurst
+	}
+	if c.HandshakeRate <= 0 {
+		c.HandshakeRate = DefaultHandshakeRate
+	}
+	if c.HandshakeBurst <= 0 {
+		c.HandshakeBurst = DefaultHandshakeBurst
+	}
+	return c
+}
+
+/*
+Token bucket: holds up to burst tokens, refilled at rate tokens per second.
+Each request takes a token and is rejected when the bucket is empty
+*/
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Result of a rate limit check
+type limitResult int
+
+const (
+	limitAllowed limitResult = iota
+	limitPeer
+	limitGlobal
+)
+
+// Per peer and global token buckets for the requests received by the node
+type RateLimiter struct {
+	lock   sync.Mutex
+	config RateLimitConfig
+	global *tokenBucket
+	peers  map[string]*tokenBucket
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	cfg = cfg.withDefaults()
+	return &RateLimiter{
+		config: cfg,
+		global: newTokenBucket(cfg.GlobalRate, cfg.GlobalBurst, time.Now()),
+		peers:  make(map[string]*tokenBucket),
+	}
+}
+
+// Limits the handshakes of each caller with the handshake rate, instead of the peer rate
+func newHandshakeLimiter(cfg RateLimitConfig) *RateLimiter {
+	cfg = cfg.withDefaults()
+	cfg.PeerRate, cfg.PeerBurst = cfg.HandshakeRate, cfg.HandshakeBurst
+	return NewRateLimiter(cfg)
+}
+
+/*
+Takes a token from the bucket of the peer and from the global bucket.
+
+The peer bucket is checked first, so a single flooding peer does not consume the global tokens
+*/
+func (l *RateLimiter) allow(key string) limitResult {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	bucket, ok := l.peers[key]
+	if !ok {
+		if len(l.peers) >= maxIdleBuckets {
+			l.dropIdle(now)
+		}
+		bucket = newTokenBucket(l.config.PeerRate, l.config.PeerBurst, now)
+		l.peers[key] = bucket
+	}
+	if !bucket.allow(now) {
+		return limitPeer
+	}
+	if !l.global.allow(now) {
+		bucket.tokens++ // the request was not served, so the peer keeps its token
+		return limitGlobal
+	}
+	return limitAllowed
+}
+
+// Removes the buckets that are full again, since they behave the same as new ones
+func (l *RateLimiter) dropIdle(now time.Time) {
+	for key, bucket := range l.peers {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.burst {
+			delete(l.peers, key)
+		}
+	}
+}
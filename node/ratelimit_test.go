@@ -0,0 +1,131 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(10, 2, now)
+	assert.True(t, bucket.allow(now))
+	assert.True(t, bucket.allow(now))
+	assert.False(t, bucket.allow(now))
+	// 10 tokens per second: a token is back after 100ms
+	assert.True(t, bucket.allow(now.Add(100*time.Millisecond)))
+	assert.False(t, bucket.allow(now.Add(100*time.Millisecond)))
+	// never holds more than burst tokens
+	later := now.Add(time.Hour)
+	assert.True(t, bucket.allow(later))
+	assert.True(t, bucket.allow(later))
+	assert.False(t, bucket.allow(later))
+}
+
+func TestRateLimiterPeer(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{PeerRate: 0.001, PeerBurst: 2})
+	assert.Equal(t, limitAllowed, limiter.allow("10.0.0.1"))
+	assert.Equal(t, limitAllowed, limiter.allow("10.0.0.1"))
+	assert.Equal(t, limitPeer, limiter.allow("10.0.0.1"))
+	// other peers have their own bucket
+	assert.Equal(t, limitAllowed, limiter.allow("10.0.0.2"))
+}
+
+func TestRateLimiterGlobal(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{GlobalRate: 0.001, GlobalBurst: 2})
+	assert.Equal(t, limitAllowed, limiter.allow("10.0.0.1"))
+	assert.Equal(t, limitAllowed, limiter.allow("10.0.0.2"))
+	assert.Equal(t, limitGlobal, limiter.allow("10.0.0.3"))
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	var (
+		n    = NewNode(ServerConfig{ListenAddr: ":3000", RateLimit: RateLimitConfig{PeerRate: 0.001, PeerBurst: 1}})
+		info = &grpc.UnaryServerInfo{FullMethod: "/Node/HandleTransaction"}
+		ctx  = peerContext("10.0.0.1:5000")
+	)
+	handler := func(ctx context.Context, req any) (any, error) {
+		return n.HandleTransaction(ctx, req.(*proto.Transaction))
+	}
+	toAddress := crypto.GeneratePrivateKey().Public().Address().Bytes()
+	_, err := n.rateLimitInterceptor(ctx, genesisTransaction(t, n.chain, toAddress, 100), info, handler)
+	require.Nil(t, err)
+	_, err = n.rateLimitInterceptor(ctx, signedTransaction(), info, handler)
+	require.NotNil(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, 1, n.mempool.Len())
+	assert.Equal(t, uint64(1), n.metrics.Snapshot()["rejected_peer_rate"])
+
+	// the admin service is not limited
+	adminInfo := &grpc.UnaryServerInfo{FullMethod: "/Admin/GetMetrics"}
+	_, err = n.rateLimitInterceptor(ctx, &proto.GetMetricsRequest{}, adminInfo, func(ctx context.Context, req any) (any, error) {
+		return NewAdminServer(n).GetMetrics(ctx, req.(*proto.GetMetricsRequest))
+	})
+	require.Nil(t, err)
+}
+
+// server stream that only has a context, for calling the stream interceptor
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	var (
+		n       = NewNode(ServerConfig{ListenAddr: ":3000", RateLimit: RateLimitConfig{PeerRate: 0.001, PeerBurst: 1}})
+		info    = &grpc.StreamServerInfo{FullMethod: "/Query/SubscribeBlocks", IsServerStream: true}
+		opened  = 0
+		handler = func(srv any, ss grpc.ServerStream) error {
+			opened++
+			return nil
+		}
+	)
+	stream := testServerStream{ctx: peerContext("10.0.0.1:5000")}
+	require.Nil(t, n.streamInterceptor(nil, stream, info, handler))
+	err := n.streamInterceptor(nil, stream, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, uint64(1), n.metrics.Snapshot()["rejected_peer_rate"])
+
+	// banned peers cannot open streams
+	require.Nil(t, n.bans.Ban("10.0.0.2", time.Now().Add(time.Hour)))
+	err = n.streamInterceptor(nil, testServerStream{ctx: peerContext("10.0.0.2:5000")}, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, 1, opened)
+}
+
+func TestMaxBroadcasts(t *testing.T) {
+	n := NewNode(ServerConfig{ListenAddr: ":3000", MaxBroadcasts: 1})
+	n.broadcasts <- struct{}{} // a broadcast is already running
+	n.goBroadcast(signedTransaction())
+	assert.Equal(t, uint64(1), n.metrics.DroppedBroadcasts.Load())
+
+	<-n.broadcasts
+	n.goBroadcast(signedTransaction())
+	assert.Equal(t, uint64(1), n.metrics.DroppedBroadcasts.Load())
+}
+
+func TestMaxMsgSize(t *testing.T) {
+	_, addr := startTestNode(t, ServerConfig{MaxMsgSize: 1024})
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	tx := signedTransaction()
+	tx.Outputs[0].Address = make([]byte, 2048)
+	_, err = proto.NewNodeClient(conn).HandleTransaction(context.Background(), tx)
+	require.NotNil(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,168 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+)
+
+// Transactions remembered as verified: enough for a full mempool and the blocks that confirm it
+const DefaultSigCacheSize = 2 * DefaultMaxMempoolTxs
+
+/*
+Keeps the hashes of the last transactions whose signatures were verified.
+
+The hash of a transaction covers its signatures, so a transaction with the same hash has the same (valid) signatures,
+and transactions verified when they entered the mempool are not verified again when their block arrives.
+When the capacity is reached, the oldest hashes are forgotten
+*/
+type SigCache struct {
+	lock     sync.RWMutex
+	capacity int
+	verified map[string]struct{}
+	order    []string // hashes in the order they were verified
+}
+
+func NewSigCache(capacity int) *SigCache {
+	return &SigCache{
+		capacity: capacity,
+		verified: make(map[string]struct{}),
+	}
+}
+
+func (c *SigCache) Add(hash string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.verified[hash]; ok {
+		return
+	}
+	c.verified[hash] = struct{}{}
+	c.order = append(c.order, hash)
+	for len(c.order) > c.capacity {
+		delete(c.verified, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+func (c *SigCache) Contains(hash string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	_, ok := c.verified[hash]
+	return ok
+}
+
+func (c *SigCache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return len(c.verified)
+}
+
+/*
+Verifies the signatures of every input of the transactions with a pool of workers (one per CPU),
+skipping the transactions in the cache (optional):
+ 1. Hashes the transactions in parallel, keeping the ones not verified yet
+ 2. Verifies the signatures of all their inputs in parallel, stopping at the first invalid one
+ 3. Adds the verified transactions to the cache
+*/
+func VerifySignatures(txs []*proto.Transaction, cache *SigCache) error {
+	type pendingTx struct {
+		hash     string
+		sigHash  []byte // message signed by the inputs
+		verified bool   // found in the cache
+	}
+	type input struct {
+		tx, index int
+	}
+	var (
+		workers = runtime.NumCPU()
+		pending = make([]pendingTx, len(txs))
+	)
+	err := parallel(len(txs), workers, func(i int) error {
+		hash := hex.EncodeToString(types.HashTransaction(txs[i]))
+		pending[i].hash = hash
+		if cache != nil && cache.Contains(hash) {
+			pending[i].verified = true
+			return nil
+		}
+		for j, in := range txs[i].Inputs {
+			if len(in.PublicKey) != crypto.PubKeyLen || len(in.Signature) != crypto.SignatureLen {
+				return fmt.Errorf("input %d of tx %s has an invalid public key or signature", j, hash)
+			}
+		}
+		pending[i].sigHash = types.HashUnsignedTransaction(txs[i])
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var inputs []input
+	for i, tx := range txs {
+		if pending[i].verified {
+			continue
+		}
+		for j := range tx.Inputs {
+			inputs = append(inputs, input{tx: i, index: j})
+		}
+	}
+	err = parallel(len(inputs), workers, func(i int) error {
+		var (
+			in     = txs[inputs[i].tx].Inputs[inputs[i].index]
+			sig    = crypto.SignatureFromBytes(in.Signature)
+			pubKey = crypto.PublicKeyFromBytes(in.PublicKey)
+		)
+		if !sig.Verify(pubKey, pending[inputs[i].tx].sigHash) {
+			return fmt.Errorf("invalid signature in input %d of tx %s", inputs[i].index, pending[inputs[i].tx].hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if cache != nil {
+		for _, p := range pending {
+			if !p.verified {
+				cache.Add(p.hash)
+			}
+		}
+	}
+	return nil
+}
+
+// Calls fn for 0..n-1 with a pool of workers, returning the first error (the remaining calls are skipped)
+func parallel(n, workers int, fn func(i int) error) error {
+	workers = min(workers, n)
+	var (
+		wg       sync.WaitGroup
+		next     atomic.Int64
+		failed   atomic.Bool
+		errOnce  sync.Once
+		firstErr error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				if err := fn(i); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
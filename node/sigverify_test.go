@@ -0,0 +1,114 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Transactions with nInputs inputs, each signed by its own key
+func signedTransactions(n, nInputs int) []*proto.Transaction {
+	txs := make([]*proto.Transaction, n)
+	for i := range txs {
+		var (
+			tx   = &proto.Transaction{Version: 1}
+			keys = make([]*crypto.PrivateKey, nInputs)
+		)
+		for j := range keys {
+			keys[j] = crypto.GeneratePrivateKey()
+			tx.Inputs = append(tx.Inputs, &proto.TxInput{
+				PrevTxHash:   util.RandomHash(),
+				PrevOutIndex: uint32(j),
+				PublicKey:    keys[j].Public().Bytes(),
+			})
+		}
+		tx.Outputs = []*proto.TxOutput{{Amount: int64(i + 1), Address: keys[0].Public().Address().Bytes()}}
+		for j, key := range keys {
+			tx.Inputs[j].Signature = types.SignTransaction(key, tx).Bytes()
+		}
+		txs[i] = tx
+	}
+	return txs
+}
+
+func TestVerifySignatures(t *testing.T) {
+	var (
+		txs   = signedTransactions(50, 3)
+		cache = NewSigCache(100)
+	)
+	require.Nil(t, VerifySignatures(txs, cache))
+	assert.Equal(t, 50, cache.Len())
+	assert.True(t, cache.Contains(hex.EncodeToString(types.HashTransaction(txs[10]))))
+
+	// an input signed by another key invalidates the whole batch
+	invalid := signedTransactions(50, 3)
+	invalid[42].Inputs[2].Signature = invalid[42].Inputs[1].Signature
+	err := VerifySignatures(invalid, cache)
+	assert.ErrorContains(t, err, "invalid signature in input 2")
+	assert.False(t, cache.Contains(hex.EncodeToString(types.HashTransaction(invalid[42]))))
+
+	invalid[42].Inputs[2].Signature = nil
+	assert.ErrorContains(t, VerifySignatures(invalid, nil), "invalid public key or signature")
+	assert.Nil(t, VerifySignatures(nil, cache))
+}
+
+func TestVerifySignaturesCache(t *testing.T) {
+	var (
+		txs   = signedTransactions(3, 1)
+		cache = NewSigCache(2)
+	)
+	// transactions in the cache are not verified again
+	txs[0].Inputs[0].Signature = txs[1].Inputs[0].Signature
+	cache.Add(hex.EncodeToString(types.HashTransaction(txs[0])))
+	assert.Nil(t, VerifySignatures(txs[:1], cache))
+
+	// the oldest hashes are forgotten
+	require.Nil(t, VerifySignatures(txs[1:], cache))
+	assert.Equal(t, 2, cache.Len())
+	assert.NotNil(t, VerifySignatures(txs[:1], cache))
+}
+
+func benchmarkVerifyBlock(b *testing.B, verify func(txs []*proto.Transaction) error) {
+	for _, n := range []int{10, 100, 1000} {
+		txs := signedTransactions(n, 2)
+		b.Run(fmt.Sprintf("txs=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := verify(txs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyBlockSerial(b *testing.B) {
+	benchmarkVerifyBlock(b, func(txs []*proto.Transaction) error {
+		for _, tx := range txs {
+			if !types.VerifyTransaction(tx) {
+				return fmt.Errorf("invalid transaction signature")
+			}
+		}
+		return nil
+	})
+}
+
+func BenchmarkVerifyBlockParallel(b *testing.B) {
+	benchmarkVerifyBlock(b, func(txs []*proto.Transaction) error {
+		return VerifySignatures(txs, nil)
+	})
+}
+
+// Blocks whose transactions were verified when they entered the mempool
+func BenchmarkVerifyBlockCached(b *testing.B) {
+	cache := NewSigCache(DefaultSigCacheSize)
+	benchmarkVerifyBlock(b, func(txs []*proto.Transaction) error {
+		return VerifySignatures(txs, cache)
+	})
+}
@@ -0,0 +1,189 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+)
+
+func (vs *ValidatorSet) clone() *ValidatorSet {
+	clone := &ValidatorSet{
+		validators: append([][]byte{}, vs.validators...),
+		blockTime:  vs.blockTime,
+		stakes:     make(map[string]string, len(vs.stakes)),
+	}
+	for validator, stake := range vs.stakes {
+		clone.stakes[validator] = stake
+	}
+	return clone
+}
+
+// Verifies if the output (key in the UTXO store) is locked as the stake of a validator
+func (vs *ValidatorSet) Staked(outputKey string) bool {
+	if vs == nil {
+		return false
+	}
+	for _, stake := range vs.stakes {
+		if stake == outputKey {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Returns the set with the validator operation of the transaction applied (its signatures must have been verified):
+  - join adds the key as the last validator, locking the first output of the transaction as its stake
+  - leave removes the validator, unlocking its stake. The last validator cannot leave
+  - rotate replaces the key of the validator by the new key, in the same position of the schedule, keeping its stake
+*/
+func (vs *ValidatorSet) apply(tx *proto.Transaction, minStake int64) (*ValidatorSet, error) {
+	var (
+		op        = tx.ValidatorOp
+		validator = hex.EncodeToString(op.PublicKey)
+		next      = vs.clone()
+	)
+	switch op.Type {
+	case proto.ValidatorOpType_VALIDATOR_OP_JOIN:
+		if vs.Contains(op.PublicKey) {
+			return nil, fmt.Errorf("%s is already a validator", validator)
+		}
+		if len(tx.Outputs) == 0 || tx.Outputs[0].Amount < minStake {
+			return nil, fmt.Errorf("the stake of a validator must be at least %d", minStake)
+		}
+		next.validators = append(next.validators, op.PublicKey)
+		next.stakes[validator] = outputKey(types.HashTransaction(tx), 0)
+	case proto.ValidatorOpType_VALIDATOR_OP_LEAVE:
+		i := vs.indexOf(op.PublicKey)
+		if i < 0 {
+			return nil, fmt.Errorf("%s is not a validator", validator)
+		}
+		if vs.Len() == 1 {
+			return nil, fmt.Errorf("the last validator cannot leave")
+		}
+		next.validators = append(next.validators[:i], next.validators[i+1:]...)
+		delete(next.stakes, validator)
+	case proto.ValidatorOpType_VALIDATOR_OP_ROTATE:
+		i := vs.indexOf(op.PublicKey)
+		if i < 0 {
+			return nil, fmt.Errorf("%s is not a validator", validator)
+		}
+		if vs.Contains(op.NewPublicKey) {
+			return nil, fmt.Errorf("%x is already a validator", op.NewPublicKey)
+		}
+		next.validators[i] = op.NewPublicKey
+		if stake, ok := next.stakes[validator]; ok {
+			delete(next.stakes, validator)
+			next.stakes[hex.EncodeToString(op.NewPublicKey)] = stake
+		}
+	default:
+		return nil, fmt.Errorf("unknown validator operation %d", op.Type)
+	}
+	return next, nil
+}
+
+func (vs *ValidatorSet) indexOf(pubKey []byte) int {
+	for i, validator := range vs.validators {
+		if bytes.Equal(validator, pubKey) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validator set resulting from the block of the height
+type validatorSetAt struct {
+	height int
+	set    *ValidatorSet
+}
+
+/*
+Validator sets resulting from the blocks of the chain: the set of the genesis and one set per block that changed it.
+The set active in an epoch is the one resulting from the last block of the previous epoch,
+so the changes of the blocks of an epoch take effect in the first block of the next one
+*/
+type validatorHistory struct {
+	lock        sync.RWMutex
+	epochLength int              // zero when the set never changes
+	sets        []validatorSetAt // oldest first
+}
+
+// History starting with the validators of the genesis (nil if the chain has no validators)
+func newValidatorHistory(genesis *ValidatorSet, epochLength int) *validatorHistory {
+	return &validatorHistory{
+		epochLength: epochLength,
+		sets:        []validatorSetAt{{height: 0, set: genesis}},
+	}
+}
+
+// Set active at the height
+func (h *validatorHistory) At(height int) *ValidatorSet {
+	boundary := 0 // last block of the previous epoch
+	if h.epochLength > 0 && height > 0 {
+		boundary = height - 1 - (height-1)%h.epochLength
+	}
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	for i := len(h.sets) - 1; i > 0; i-- {
+		if h.sets[i].height <= boundary {
+			return h.sets[i].set
+		}
+	}
+	return h.sets[0].set
+}
+
+// Set resulting from all the blocks of the chain, which becomes active in the next epoch
+func (h *validatorHistory) Latest() *ValidatorSet {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.sets[len(h.sets)-1].set
+}
+
+func (h *validatorHistory) add(height int, set *ValidatorSet) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.sets = append(h.sets, validatorSetAt{height: height, set: set})
+}
+
+// Removes the sets resulting from the blocks from the height on (reverted blocks)
+func (h *validatorHistory) revert(height int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for len(h.sets) > 1 && h.sets[len(h.sets)-1].height >= height {
+		h.sets = h.sets[:len(h.sets)-1]
+	}
+}
+
+// Validator set active at the height (nil if the chain has no validators)
+func (c *Chain) Validators(height int) *ValidatorSet {
+	return c.validators.At(height)
+}
+
+/*
+Returns the set with the validator operation of the transaction applied, or the same set if the transaction has none.
+Validator transactions must spend outputs like the others, so they cannot be replayed
+*/
+func (c *Chain) applyValidatorOp(vs *ValidatorSet, tx *proto.Transaction) (*ValidatorSet, error) {
+	if tx.ValidatorOp == nil {
+		return vs, nil
+	}
+	if c.genesis.Staking == nil || vs == nil {
+		return nil, fmt.Errorf("the validator set of the chain does not change")
+	}
+	if len(tx.Inputs) == 0 {
+		return nil, fmt.Errorf("validator transaction without inputs")
+	}
+	if !types.VerifyValidatorOp(tx) {
+		return nil, fmt.Errorf("invalid validator signature")
+	}
+	return vs.apply(tx, c.genesis.Staking.MinStake)
+}
+
+// Verifies if the output is the stake of a validator of the latest set or of the set of the next block
+func (c *Chain) staked(outputKey string) bool {
+	return c.validators.Latest().Staked(outputKey) || c.validators.At(c.Height()+1).Staked(outputKey)
+}
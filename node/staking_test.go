@@ -0,0 +1,132 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Transaction making the validator operation, which sends the output of the funder back to it
+func validatorTx(funder *crypto.PrivateKey, prev *proto.Transaction, index uint32, op *proto.ValidatorOp, key, newKey *crypto.PrivateKey) *proto.Transaction {
+	tx := &proto.Transaction{
+		Version:     1,
+		Inputs:      []*proto.TxInput{{PrevTxHash: types.HashTransaction(prev), PrevOutIndex: index, PublicKey: funder.Public().Bytes()}},
+		Outputs:     []*proto.TxOutput{{Amount: prev.Outputs[index].Amount, Address: funder.Public().Address().Bytes()}},
+		ValidatorOp: op,
+	}
+	types.SignValidatorOp(tx, key, newKey)
+	tx.Inputs[0].Signature = types.SignTransaction(funder, tx).Bytes()
+	return tx
+}
+
+func TestValidatorSetChanges(t *testing.T) {
+	var (
+		funder  = crypto.GeneratePrivateKey()
+		a, b, c = crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()
+		genesis = testGenesis(withValidators(a), withStaking(2, funder))
+		start   = genesis.Timestamp
+	)
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), genesis)
+	require.Nil(t, err)
+	assert.True(t, chain.Consensus().Authorized(b.Public().Bytes()))
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	allocation := genesisBlock.Transactions[0]
+
+	// b joins in the first epoch (heights 1 and 2) and becomes a validator in the second one
+	join := validatorTx(funder, allocation, 0, &proto.ValidatorOp{Type: proto.ValidatorOpType_VALIDATOR_OP_JOIN, PublicKey: b.Public().Bytes()}, b, nil)
+	require.Nil(t, chain.ValidateTransaction(join))
+	b1 := blockOn(tip(t, chain), signedBy(a), at(start.Add(time.Second)), withTxs(join))
+	_, err = chain.AddBlock(b1)
+	require.Nil(t, err)
+	assert.Equal(t, 1, chain.Validators(2).Len())
+	assert.Equal(t, 2, chain.Validators(3).Len())
+	assert.ErrorContains(t, chain.ValidateTransaction(join), "already spent")
+
+	// the stake is locked while b is a validator
+	spend := &proto.Transaction{
+		Version: 1,
+		Inputs:  []*proto.TxInput{{PrevTxHash: types.HashTransaction(join), PublicKey: funder.Public().Bytes()}},
+		Outputs: []*proto.TxOutput{{Amount: 10, Address: funder.Public().Address().Bytes()}},
+	}
+	spend.Inputs[0].Signature = types.SignTransaction(funder, spend).Bytes()
+	assert.ErrorContains(t, chain.ValidateTransaction(spend), "stake of a validator")
+	rejoin := validatorTx(funder, allocation, 1, &proto.ValidatorOp{Type: proto.ValidatorOpType_VALIDATOR_OP_JOIN, PublicKey: b.Public().Bytes()}, b, nil)
+	assert.ErrorContains(t, chain.ValidateTransaction(rejoin), "already a validator")
+
+	// the leaders of the second epoch are chosen among a and b
+	_, err = chain.AddBlock(blockOn(tip(t, chain), signedBy(a), at(start.Add(2*time.Second))))
+	require.Nil(t, err)
+	_, err = chain.AddBlock(blockOn(tip(t, chain), signedBy(a), at(start.Add(3*time.Second))))
+	assert.ErrorContains(t, err, "the leader is")
+
+	// b rotates its key to c, which keeps its stake and its turn from the next epoch
+	rotate := validatorTx(funder, allocation, 1, &proto.ValidatorOp{
+		Type:         proto.ValidatorOpType_VALIDATOR_OP_ROTATE,
+		PublicKey:    b.Public().Bytes(),
+		NewPublicKey: c.Public().Bytes(),
+	}, b, c)
+	b3 := blockOn(tip(t, chain), signedBy(b), at(start.Add(3*time.Second)), withTxs(rotate))
+	_, err = chain.AddBlock(b3)
+	require.Nil(t, err)
+	assert.True(t, chain.Validators(4).Contains(b.Public().Bytes()))
+	assert.True(t, chain.Validators(5).Contains(c.Public().Bytes()))
+	assert.False(t, chain.Validators(5).Contains(b.Public().Bytes()))
+	assert.ErrorContains(t, chain.ValidateTransaction(spend), "stake of a validator")
+}
+
+func TestValidatorOpRules(t *testing.T) {
+	var (
+		funder = crypto.GeneratePrivateKey()
+		a, b   = crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()
+	)
+	chain, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), testGenesis(withValidators(a), withStaking(10, funder)))
+	require.Nil(t, err)
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	allocation := genesisBlock.Transactions[0]
+
+	leave := validatorTx(funder, allocation, 0, &proto.ValidatorOp{Type: proto.ValidatorOpType_VALIDATOR_OP_LEAVE, PublicKey: a.Public().Bytes()}, a, nil)
+	assert.ErrorContains(t, chain.ValidateTransaction(leave), "last validator")
+	leave = validatorTx(funder, allocation, 0, &proto.ValidatorOp{Type: proto.ValidatorOpType_VALIDATOR_OP_LEAVE, PublicKey: b.Public().Bytes()}, b, nil)
+	assert.ErrorContains(t, chain.ValidateTransaction(leave), "is not a validator")
+	forged := validatorTx(funder, allocation, 0, &proto.ValidatorOp{Type: proto.ValidatorOpType_VALIDATOR_OP_LEAVE, PublicKey: a.Public().Bytes()}, b, nil)
+	assert.ErrorContains(t, chain.ValidateTransaction(forged), "invalid validator signature")
+	join := validatorTx(funder, allocation, 1, &proto.ValidatorOp{Type: proto.ValidatorOpType_VALIDATOR_OP_JOIN, PublicKey: b.Public().Bytes()}, b, nil)
+	join.Outputs[0].Amount = 50 // lower than the minimum stake
+	types.SignValidatorOp(join, b, nil)
+	join.Inputs[0].Signature = types.SignTransaction(funder, join).Bytes()
+	assert.ErrorContains(t, chain.ValidateTransaction(join), "at least 100")
+
+	// the validators of a chain without staking do not change
+	static, err := NewChainWithGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), testGenesis(withValidators(a)))
+	require.Nil(t, err)
+	join = validatorTx(funder, allocation, 0, &proto.ValidatorOp{Type: proto.ValidatorOpType_VALIDATOR_OP_JOIN, PublicKey: b.Public().Bytes()}, b, nil)
+	_, err = static.applyValidatorOp(static.Validators(1), join)
+	assert.ErrorContains(t, err, "does not change")
+	assert.False(t, static.Consensus().Authorized(b.Public().Bytes()))
+}
+
+func TestValidatorHistoryRevert(t *testing.T) {
+	var (
+		genesis = &ValidatorSet{validators: [][]byte{{1}}}
+		joined  = &ValidatorSet{validators: [][]byte{{1}, {2}}}
+		history = newValidatorHistory(genesis, 5)
+	)
+	history.add(3, joined)
+	assert.Equal(t, genesis, history.At(5))
+	assert.Equal(t, joined, history.At(6))
+	assert.Equal(t, joined, history.Latest())
+
+	// reverting the block that changed the set restores the previous one
+	history.revert(4)
+	assert.Equal(t, joined, history.Latest())
+	history.revert(3)
+	assert.Equal(t, genesis, history.Latest())
+	assert.Equal(t, genesis, history.At(6))
+}
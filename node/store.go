@@ -3,6 +3,7 @@ package node
 import (
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/CaiqueRibeiro/blocker/proto"
@@ -12,27 +13,63 @@ import (
 type UTXOStorer interface {
 	Put(*UTXO) error
 	Get(string) (*UTXO, error)
+	Delete(string) error
+	ListByAddress([]byte) ([]*UTXO, error)
 }
 
+/*
+Keeps copies of the outputs, so the chain updating them and the queries reading them never share an UTXO.
+Only the unspent outputs are indexed by address
+*/
 type MemoryUTXOStore struct {
-	lock sync.RWMutex
-	data map[string]*UTXO
+	lock      sync.RWMutex
+	data      map[string]*UTXO
+	byAddress map[string]map[string]struct{} // keys of the unspent UTXOs by hex encoded address
 }
 
 func NewMemoryUTXOStore() *MemoryUTXOStore {
 	return &MemoryUTXOStore{
-		data: make(map[string]*UTXO),
+		data:      make(map[string]*UTXO),
+		byAddress: make(map[string]map[string]struct{}),
 	}
 }
 
 func (s *MemoryUTXOStore) Put(utxo *UTXO) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	key := fmt.Sprintf("%s_%d", utxo.Hash, utxo.OutIndex)
-	s.data[key] = utxo
+	key := utxoKey(utxo.Hash, utxo.OutIndex)
+	stored := *utxo
+	s.data[key] = &stored
+	if utxo.Spent {
+		s.unindex(utxo.Address, key)
+		return nil
+	}
+	address := hex.EncodeToString(utxo.Address)
+	if _, ok := s.byAddress[address]; !ok {
+		s.byAddress[address] = make(map[string]struct{})
+	}
+	s.byAddress[address][key] = struct{}{}
 	return nil
 }
 
+// Returns the unspent outputs sent to the address, sorted by tx hash and output index
+func (s *MemoryUTXOStore) ListByAddress(address []byte) ([]*UTXO, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	utxos := []*UTXO{}
+	for key := range s.byAddress[hex.EncodeToString(address)] {
+		utxo := *s.data[key]
+		utxos = append(utxos, &utxo)
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		if utxos[i].Hash != utxos[j].Hash {
+			return utxos[i].Hash < utxos[j].Hash
+		}
+		return utxos[i].OutIndex < utxos[j].OutIndex
+	})
+	return utxos, nil
+}
+
 func (s *MemoryUTXOStore) Get(hash string) (*UTXO, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -40,12 +77,36 @@ func (s *MemoryUTXOStore) Get(hash string) (*UTXO, error) {
 	if !ok {
 		return nil, fmt.Errorf("could not find UTXO with hash %s", hash)
 	}
-	return utxo, nil
+	stored := *utxo
+	return &stored, nil
+}
+
+// Removes the output (created by a block reverted from the chain)
+func (s *MemoryUTXOStore) Delete(hash string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	utxo, ok := s.data[hash]
+	if !ok {
+		return fmt.Errorf("could not find UTXO with hash %s", hash)
+	}
+	delete(s.data, hash)
+	s.unindex(utxo.Address, hash)
+	return nil
+}
+
+// Removes the output from the index of the address (must be called with the lock held)
+func (s *MemoryUTXOStore) unindex(address []byte, key string) {
+	encoded := hex.EncodeToString(address)
+	delete(s.byAddress[encoded], key)
+	if len(s.byAddress[encoded]) == 0 {
+		delete(s.byAddress, encoded)
+	}
 }
 
 type TXStorer interface {
 	Put(*proto.Transaction) error
 	Get(string) (*proto.Transaction, error)
+	Delete(string) error
 }
 
 type MemoryTXStore struct {
@@ -77,6 +138,13 @@ func (s *MemoryTXStore) Get(hash string) (*proto.Transaction, error) {
 	return tx, nil
 }
 
+func (s *MemoryTXStore) Delete(hash string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.txx, hash)
+	return nil
+}
+
 type BlockStorer interface {
 	Put(*proto.Block) error
 	Get(string) (*proto.Block, error)
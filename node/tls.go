@@ -0,0 +1,118 @@
+package node
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+/*
+TLS configuration used in the connections between nodes.
+
+Certificates can be informed by file path or in memory (PEM encoded). When both are informed, the in memory one is used.
+The same certificate is used to serve and to dial other nodes, so it can be verified by peers that require mutual TLS
+*/
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string // CA used to verify the certificates of other nodes. Empty uses the system roots
+
+	CertPEM []byte
+	KeyPEM  []byte
+	CAPEM   []byte
+
+	RequireClientCert bool   // mutual TLS: requires and verifies the certificate of the nodes that connect
+	ServerName        string // name expected in the certificate of dialed nodes. Empty uses the dialed host
+}
+
+func (c *TLSConfig) certificate() (tls.Certificate, error) {
+	if len(c.CertPEM) > 0 || len(c.KeyPEM) > 0 {
+		return tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+	}
+	return tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+}
+
+func (c *TLSConfig) certPool() (*x509.CertPool, error) {
+	caPEM := c.CAPEM
+	if len(caPEM) == 0 {
+		if c.CAFile == "" {
+			return nil, nil
+		}
+		b, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		caPEM = b
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("could not parse CA certificates")
+	}
+	return pool, nil
+}
+
+func (c *TLSConfig) hasCertificate() bool {
+	return len(c.CertPEM) > 0 || c.CertFile != ""
+}
+
+// Credentials used by the gRPC server of the node
+func (c *TLSConfig) ServerCredentials() (credentials.TransportCredentials, error) {
+	cert, err := c.certificate()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := c.certPool()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.RequireClientCert {
+		if pool == nil {
+			return nil, fmt.Errorf("a CA is required to verify client certificates")
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// Credentials used when dialing other nodes. The node certificate is presented when there is one
+func (c *TLSConfig) ClientCredentials() (credentials.TransportCredentials, error) {
+	pool, err := c.certPool()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		RootCAs:    pool,
+		ServerName: c.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.hasCertificate() {
+		cert, err := c.certificate()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// Returns the dial option with the transport credentials for the config (insecure when there is no TLS config)
+func dialCredentials(c *TLSConfig) (grpc.DialOption, error) {
+	if c == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+	creds, err := c.ClientCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(creds), nil
+}
@@ -0,0 +1,139 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "blocker test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	return &testCA{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// Issues a certificate valid for 127.0.0.1, to be used both as server and client
+func (ca *testCA) issue(t *testing.T, serial int64) *TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "blocker node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+	return &TLSConfig{
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		CAPEM:   ca.certPEM,
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// Starts a node in background, waits until it accepts connections and stops it in the end of the test
+func startTestNode(t *testing.T, cfg ServerConfig) (*Node, string) {
+	addr := freeAddr(t)
+	cfg.ListenAddr = addr
+	n := NewNode(cfg)
+	go n.Start(context.Background())
+	t.Cleanup(n.Stop)
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+	return n, addr
+}
+
+func TestTLSHandshake(t *testing.T) {
+	ca := newTestCA(t)
+	server, addr := startTestNode(t, ServerConfig{TLS: ca.issue(t, 2)})
+	client := NewNode(ServerConfig{ListenAddr: freeAddr(t), TLS: ca.issue(t, 3)})
+
+	peer, err := client.dialRemoteWork(context.Background(), addr)
+	require.Nil(t, err)
+	assert.Equal(t, server.NodeKey.Public().Bytes(), peer.version.PublicKey)
+}
+
+func TestTLSUnknownServer(t *testing.T) {
+	_, addr := startTestNode(t, ServerConfig{TLS: newTestCA(t).issue(t, 2)})
+	// the server certificate was issued by a CA the client does not trust
+	client := NewNode(ServerConfig{ListenAddr: freeAddr(t), TLS: newTestCA(t).issue(t, 3)})
+
+	_, err := client.dialRemoteWork(context.Background(), addr)
+	require.NotNil(t, err)
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverTLS := ca.issue(t, 2)
+	serverTLS.RequireClientCert = true
+	_, addr := startTestNode(t, ServerConfig{TLS: serverTLS})
+
+	// a client that trusts the server but has no certificate is refused
+	anonymous := NewNode(ServerConfig{ListenAddr: freeAddr(t), TLS: &TLSConfig{CAPEM: ca.certPEM}})
+	_, err := anonymous.dialRemoteWork(context.Background(), addr)
+	require.NotNil(t, err)
+
+	client := NewNode(ServerConfig{ListenAddr: freeAddr(t), TLS: ca.issue(t, 3)})
+	_, err = client.dialRemoteWork(context.Background(), addr)
+	require.Nil(t, err)
+}
+
+func TestInsecureClientRejectedByTLSServer(t *testing.T) {
+	_, addr := startTestNode(t, ServerConfig{TLS: newTestCA(t).issue(t, 2)})
+	client := NewNode(ServerConfig{ListenAddr: freeAddr(t)})
+
+	_, err := client.dialRemoteWork(context.Background(), addr)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,44 @@
+package node
+
+import "sync"
+
+const maxRejectedTxs = 10000
+
+/*
+Keeps the reason of the last rejected transactions by hash, so clients can query why a transaction was rejected.
+
+When the capacity is reached, the oldest rejections are forgotten
+*/
+type RejectedTxs struct {
+	lock     sync.RWMutex
+	capacity int
+	reasons  map[string]string
+	order    []string // hashes in the order they were rejected
+}
+
+func NewRejectedTxs(capacity int) *RejectedTxs {
+	return &RejectedTxs{
+		capacity: capacity,
+		reasons:  make(map[string]string),
+	}
+}
+
+func (r *RejectedTxs) Add(hash string, reason string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.reasons[hash]; !ok {
+		r.order = append(r.order, hash)
+	}
+	r.reasons[hash] = reason
+	for len(r.order) > r.capacity {
+		delete(r.reasons, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+func (r *RejectedTxs) Get(hash string) (string, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	reason, ok := r.reasons[hash]
+	return reason, ok
+}
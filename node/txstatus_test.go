@@ -0,0 +1,223 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func txStatus(t *testing.T, n *Node, hash []byte) *proto.TransactionStatus {
+	query := NewQueryServer(n.chain, n.mempool, n.rejected, n.events)
+	status, err := query.GetTransactionStatus(context.Background(), &proto.GetTransactionStatusRequest{Hash: hash})
+	require.Nil(t, err)
+	return status
+}
+
+// Creates a block with the transactions in the mempool and adds it to the chain, as the validator loop does
+func mineBlock(t *testing.T, n *Node) *proto.Block {
+	block := blockOn(tip(t, n.chain), withTxs(n.mempool.Clear()...))
+	_, err := n.chain.AddBlock(block)
+	require.Nil(t, err)
+	return block
+}
+
+func TestTransactionStatus(t *testing.T) {
+	var (
+		n         = NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()})
+		ctx       = peerContext("10.0.0.1:5000")
+		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		tx        = genesisTransaction(t, n.chain, toAddress, 100)
+	)
+	assert.Equal(t, proto.TxStatus_TX_STATUS_UNKNOWN, txStatus(t, n, types.HashTransaction(tx)).Status)
+
+	ack, err := n.HandleTransaction(ctx, tx)
+	require.Nil(t, err)
+	assert.True(t, ack.Accepted)
+	assert.Equal(t, types.HashTransaction(tx), ack.Hash)
+	assert.Equal(t, proto.TxStatus_TX_STATUS_PENDING, txStatus(t, n, ack.Hash).Status)
+
+	// spends the same genesis output while the first transaction is pending
+	doubleSpend := genesisTransaction(t, n.chain, toAddress, 200)
+	doubleSpendAck, err := n.HandleTransaction(ctx, doubleSpend)
+	require.Nil(t, err)
+	assert.False(t, doubleSpendAck.Accepted)
+	status := txStatus(t, n, doubleSpendAck.Hash)
+	assert.Equal(t, proto.TxStatus_TX_STATUS_REJECTED, status.Status)
+	assert.Equal(t, doubleSpendAck.Error, status.Error)
+
+	block := mineBlock(t, n)
+	status = txStatus(t, n, ack.Hash)
+	assert.Equal(t, proto.TxStatus_TX_STATUS_CONFIRMED, status.Status)
+	assert.Equal(t, types.HashBlock(block), status.BlockHash)
+	assert.Equal(t, int32(1), status.BlockHeight)
+	assert.Equal(t, int32(1), status.Confirmations)
+
+	mineBlock(t, n)
+	assert.Equal(t, int32(2), txStatus(t, n, ack.Hash).Confirmations)
+
+	// sending a confirmed transaction again is not an error
+	ack, err = n.HandleTransaction(ctx, tx)
+	require.Nil(t, err)
+	assert.True(t, ack.Accepted)
+}
+
+func TestHandleBlock(t *testing.T) {
+	var (
+		validator = NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()})
+		n         = NewNode(ServerConfig{ListenAddr: ":4000"})
+		ctx       = peerContext("10.0.0.1:5000")
+		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		tx        = genesisTransaction(t, n.chain, toAddress, 100)
+	)
+	for _, node := range []*Node{validator, n} {
+		ack, err := node.HandleTransaction(ctx, tx)
+		require.Nil(t, err)
+		require.True(t, ack.Accepted)
+	}
+	block := mineBlock(t, validator)
+
+	ack, err := n.HandleBlock(ctx, block)
+	require.Nil(t, err)
+	assert.True(t, ack.Accepted)
+	assert.Equal(t, 1, n.chain.Height())
+	assert.Equal(t, 0, n.mempool.Len())
+	assert.Equal(t, proto.TxStatus_TX_STATUS_CONFIRMED, txStatus(t, n, types.HashTransaction(tx)).Status)
+
+	// the same block again is ignored
+	ack, err = n.HandleBlock(ctx, block)
+	require.Nil(t, err)
+	assert.True(t, ack.Accepted)
+	assert.Equal(t, 1, n.chain.Height())
+}
+
+func TestHandleForkBlock(t *testing.T) {
+	var (
+		validator = NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()})
+		n         = NewNode(ServerConfig{ListenAddr: ":4000"})
+		ctx       = peerContext("10.0.0.1:5000")
+		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		tx        = genesisTransaction(t, n.chain, toAddress, 100)
+	)
+	ack, err := n.HandleBlock(ctx, mineBlock(t, validator))
+	require.Nil(t, err)
+	require.True(t, ack.Accepted)
+	ack, err = n.HandleTransaction(ctx, tx)
+	require.Nil(t, err)
+	require.True(t, ack.Accepted)
+
+	// a fork as long as the chain is stored, but its transactions are still pending
+	genesis, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	ack, err = n.HandleBlock(ctx, blockOn(genesis, withTxs(tx)))
+	require.Nil(t, err)
+	assert.True(t, ack.Accepted)
+	assert.Equal(t, 1, n.chain.Height())
+	assert.True(t, n.mempool.Has(tx))
+}
+
+func TestHandleInvalidBlock(t *testing.T) {
+	var (
+		key = crypto.GeneratePrivateKey()
+		n   = NewNode(ServerConfig{ListenAddr: ":4000"})
+	)
+	block, err := NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: key}).createBlock(nil)
+	require.Nil(t, err)
+	block.Header.Height = 5
+	types.SignBlock(key, block)
+
+	ack, err := n.HandleBlock(peerContext("10.0.0.1:5000"), block)
+	require.Nil(t, err)
+	assert.False(t, ack.Accepted)
+	assert.True(t, n.bans.IsBanned("10.0.0.1"))
+
+	// a block whose parent is not known yet is not a fault of the peer
+	ack, err = n.HandleBlock(peerContext("10.0.0.2:5000"), blockOn(blockOn(tip(t, n.chain))))
+	require.Nil(t, err)
+	assert.False(t, ack.Accepted)
+	assert.False(t, n.bans.IsBanned("10.0.0.2"))
+
+	// neither is a block ahead of the clock of the node
+	ack, err = n.HandleBlock(peerContext("10.0.0.3:5000"), blockOn(tip(t, n.chain), at(time.Now().Add(3*time.Hour))))
+	require.Nil(t, err)
+	assert.False(t, ack.Accepted)
+	assert.Equal(t, 0, n.scores.Score("10.0.0.3"))
+}
+
+func TestMempoolRemoveBlock(t *testing.T) {
+	var (
+		chain     = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		mempool   = NewMemPool()
+		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		tx        = genesisTransaction(t, chain, toAddress, 100)
+		conflict  = genesisTransaction(t, chain, toAddress, 200)
+	)
+	require.True(t, mempool.Add(tx))
+	require.False(t, mempool.Add(tx))
+	// spends the same output of a pending transaction
+	require.False(t, mempool.Add(conflict))
+
+	// a block with the conflicting transaction arrives from another validator
+	mempool = NewMemPool()
+	require.True(t, mempool.Add(tx))
+	conflicts := mempool.RemoveBlock(&proto.Block{Transactions: []*proto.Transaction{conflict}})
+	assert.Equal(t, []string{hex.EncodeToString(types.HashTransaction(tx))}, conflicts)
+	assert.Equal(t, 0, mempool.Len())
+	// the output is free to be spent again in the mempool
+	assert.True(t, mempool.Add(conflict))
+}
+
+func TestMempoolMaxTxs(t *testing.T) {
+	n := NewNode(ServerConfig{ListenAddr: ":3000", MaxMempoolTxs: 1})
+	require.True(t, n.mempool.Add(signedTransaction()))
+	assert.True(t, n.mempool.Full())
+	assert.False(t, n.mempool.Add(signedTransaction()))
+
+	ack, err := n.HandleTransaction(peerContext("10.0.0.1:5000"), genesisTransaction(t, n.chain, crypto.GeneratePrivateKey().Public().Address().Bytes(), 100))
+	require.Nil(t, err)
+	assert.False(t, ack.Accepted)
+	assert.Equal(t, "mempool is full", ack.Error)
+}
+
+func TestRejectedTxsCapacity(t *testing.T) {
+	rejected := NewRejectedTxs(2)
+	rejected.Add("a", "reason a")
+	rejected.Add("b", "reason b")
+	rejected.Add("c", "reason c")
+	_, ok := rejected.Get("a")
+	assert.False(t, ok)
+	reason, ok := rejected.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, "reason c", reason)
+}
+
+func TestReorgRestoresMempool(t *testing.T) {
+	n, _ := startTestNode(t, ServerConfig{})
+	var (
+		ctx       = peerContext("10.0.0.1:5000")
+		toAddress = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		tx        = genesisTransaction(t, n.chain, toAddress, 100)
+	)
+	genesis, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	ack, err := n.HandleBlock(ctx, blockOn(genesis, withTxs(tx)))
+	require.Nil(t, err)
+	require.True(t, ack.Accepted)
+
+	// a longer fork without the transaction reverts it, so it is pending again
+	f1 := blockOn(genesis)
+	for _, b := range []*proto.Block{f1, blockOn(f1)} {
+		ack, err = n.HandleBlock(ctx, b)
+		require.Nil(t, err)
+		require.True(t, ack.Accepted)
+	}
+	assert.Equal(t, 2, n.chain.Height())
+	require.Eventually(t, func() bool { return n.mempool.Has(tx) }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, proto.TxStatus_TX_STATUS_PENDING, txStatus(t, n, types.HashTransaction(tx)).Status)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/CaiqueRibeiro/blocker/proto"
+)
+
+// Lists the peers connected to the node, using its admin service
+func runPeers(args []string) error {
+	var (
+		fs       = newFlagSet("peers")
+		addr     = fs.String("admin", ":3000", "address of the admin service of the node")
+		token    = fs.String("token", "", "admin token of the node")
+		tlsFlags = addTLSFlags(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conn, err := dial(*addr, tlsFlags)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx := withAdminToken(context.Background(), *token)
+	list, err := proto.NewAdminClient(conn).ListPeers(ctx, &proto.ListPeersRequest{})
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tADDRESS\tVERSION\tHEIGHT")
+	for _, peer := range list.Peers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", peer.Id, peer.ListenAddr, peer.Version, peer.Height)
+	}
+	return w.Flush()
+}
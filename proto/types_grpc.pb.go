@@ -22,8 +22,11 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type NodeClient interface {
+	RequestChallenge(ctx context.Context, in *Challenge, opts ...grpc.CallOption) (*Challenge, error)
 	Handshake(ctx context.Context, in *Version, opts ...grpc.CallOption) (*Version, error)
 	HandleTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*Ack, error)
+	HandleBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Ack, error)
+	HandleVote(ctx context.Context, in *Vote, opts ...grpc.CallOption) (*Ack, error)
 }
 
 type nodeClient struct {
@@ -34,6 +37,15 @@ func NewNodeClient(cc grpc.ClientConnInterface) NodeClient {
 	return &nodeClient{cc}
 }
 
+func (c *nodeClient) RequestChallenge(ctx context.Context, in *Challenge, opts ...grpc.CallOption) (*Challenge, error) {
+	out := new(Challenge)
+	err := c.cc.Invoke(ctx, "/Node/RequestChallenge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *nodeClient) Handshake(ctx context.Context, in *Version, opts ...grpc.CallOption) (*Version, error) {
 	out := new(Version)
 	err := c.cc.Invoke(ctx, "/Node/Handshake", in, out, opts...)
@@ -52,12 +64,33 @@ func (c *nodeClient) HandleTransaction(ctx context.Context, in *Transaction, opt
 	return out, nil
 }
 
+func (c *nodeClient) HandleBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Ack, error) {
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, "/Node/HandleBlock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) HandleVote(ctx context.Context, in *Vote, opts ...grpc.CallOption) (*Ack, error) {
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, "/Node/HandleVote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NodeServer is the server API for Node service.
 // All implementations must embed UnimplementedNodeServer
 // for forward compatibility
 type NodeServer interface {
+	RequestChallenge(context.Context, *Challenge) (*Challenge, error)
 	Handshake(context.Context, *Version) (*Version, error)
 	HandleTransaction(context.Context, *Transaction) (*Ack, error)
+	HandleBlock(context.Context, *Block) (*Ack, error)
+	HandleVote(context.Context, *Vote) (*Ack, error)
 	mustEmbedUnimplementedNodeServer()
 }
 
@@ -65,12 +98,21 @@ type NodeServer interface {
 type UnimplementedNodeServer struct {
 }
 
+func (UnimplementedNodeServer) RequestChallenge(context.Context, *Challenge) (*Challenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestChallenge not implemented")
+}
 func (UnimplementedNodeServer) Handshake(context.Context, *Version) (*Version, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
 }
 func (UnimplementedNodeServer) HandleTransaction(context.Context, *Transaction) (*Ack, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HandleTransaction not implemented")
 }
+func (UnimplementedNodeServer) HandleBlock(context.Context, *Block) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleBlock not implemented")
+}
+func (UnimplementedNodeServer) HandleVote(context.Context, *Vote) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleVote not implemented")
+}
 func (UnimplementedNodeServer) mustEmbedUnimplementedNodeServer() {}
 
 // UnsafeNodeServer may be embedded to opt out of forward compatibility for this service.
@@ -84,6 +126,24 @@ func RegisterNodeServer(s grpc.ServiceRegistrar, srv NodeServer) {
 	s.RegisterService(&Node_ServiceDesc, srv)
 }
 
+func _Node_RequestChallenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Challenge)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).RequestChallenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/RequestChallenge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).RequestChallenge(ctx, req.(*Challenge))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Node_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Version)
 	if err := dec(in); err != nil {
@@ -120,6 +180,42 @@ func _Node_HandleTransaction_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Node_HandleBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Block)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/HandleBlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleBlock(ctx, req.(*Block))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_HandleVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vote)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/HandleVote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleVote(ctx, req.(*Vote))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Node_ServiceDesc is the grpc.ServiceDesc for Node service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -127,6 +223,10 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "Node",
 	HandlerType: (*NodeServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RequestChallenge",
+			Handler:    _Node_RequestChallenge_Handler,
+		},
 		{
 			MethodName: "Handshake",
 			Handler:    _Node_Handshake_Handler,
@@ -135,6 +235,916 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "HandleTransaction",
 			Handler:    _Node_HandleTransaction_Handler,
 		},
+		{
+			MethodName: "HandleBlock",
+			Handler:    _Node_HandleBlock_Handler,
+		},
+		{
+			MethodName: "HandleVote",
+			Handler:    _Node_HandleVote_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/types.proto",
+}
+
+// QueryClient is the client API for Query service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type QueryClient interface {
+	GetBlockByHeight(ctx context.Context, in *GetBlockByHeightRequest, opts ...grpc.CallOption) (*Block, error)
+	GetBlockByHash(ctx context.Context, in *GetBlockByHashRequest, opts ...grpc.CallOption) (*Block, error)
+	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Transaction, error)
+	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*Balance, error)
+	ListUTXOs(ctx context.Context, in *ListUTXOsRequest, opts ...grpc.CallOption) (*UTXOList, error)
+	GetTransactionStatus(ctx context.Context, in *GetTransactionStatusRequest, opts ...grpc.CallOption) (*TransactionStatus, error)
+	SubscribeBlocks(ctx context.Context, in *SubscribeBlocksRequest, opts ...grpc.CallOption) (Query_SubscribeBlocksClient, error)
+	SubscribeMempool(ctx context.Context, in *SubscribeMempoolRequest, opts ...grpc.CallOption) (Query_SubscribeMempoolClient, error)
+	SubscribeAddress(ctx context.Context, in *SubscribeAddressRequest, opts ...grpc.CallOption) (Query_SubscribeAddressClient, error)
+}
+
+type queryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQueryClient(cc grpc.ClientConnInterface) QueryClient {
+	return &queryClient{cc}
+}
+
+func (c *queryClient) GetBlockByHeight(ctx context.Context, in *GetBlockByHeightRequest, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	err := c.cc.Invoke(ctx, "/Query/GetBlockByHeight", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetBlockByHash(ctx context.Context, in *GetBlockByHashRequest, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	err := c.cc.Invoke(ctx, "/Query/GetBlockByHash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, "/Query/GetTransaction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*Balance, error) {
+	out := new(Balance)
+	err := c.cc.Invoke(ctx, "/Query/GetBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) ListUTXOs(ctx context.Context, in *ListUTXOsRequest, opts ...grpc.CallOption) (*UTXOList, error) {
+	out := new(UTXOList)
+	err := c.cc.Invoke(ctx, "/Query/ListUTXOs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetTransactionStatus(ctx context.Context, in *GetTransactionStatusRequest, opts ...grpc.CallOption) (*TransactionStatus, error) {
+	out := new(TransactionStatus)
+	err := c.cc.Invoke(ctx, "/Query/GetTransactionStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) SubscribeBlocks(ctx context.Context, in *SubscribeBlocksRequest, opts ...grpc.CallOption) (Query_SubscribeBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Query_ServiceDesc.Streams[0], "/Query/SubscribeBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &querySubscribeBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Query_SubscribeBlocksClient interface {
+	Recv() (*Block, error)
+	grpc.ClientStream
+}
+
+type querySubscribeBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *querySubscribeBlocksClient) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *queryClient) SubscribeMempool(ctx context.Context, in *SubscribeMempoolRequest, opts ...grpc.CallOption) (Query_SubscribeMempoolClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Query_ServiceDesc.Streams[1], "/Query/SubscribeMempool", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &querySubscribeMempoolClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Query_SubscribeMempoolClient interface {
+	Recv() (*Transaction, error)
+	grpc.ClientStream
+}
+
+type querySubscribeMempoolClient struct {
+	grpc.ClientStream
+}
+
+func (x *querySubscribeMempoolClient) Recv() (*Transaction, error) {
+	m := new(Transaction)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *queryClient) SubscribeAddress(ctx context.Context, in *SubscribeAddressRequest, opts ...grpc.CallOption) (Query_SubscribeAddressClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Query_ServiceDesc.Streams[2], "/Query/SubscribeAddress", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &querySubscribeAddressClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Query_SubscribeAddressClient interface {
+	Recv() (*AddressEvent, error)
+	grpc.ClientStream
+}
+
+type querySubscribeAddressClient struct {
+	grpc.ClientStream
+}
+
+func (x *querySubscribeAddressClient) Recv() (*AddressEvent, error) {
+	m := new(AddressEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// QueryServer is the server API for Query service.
+// All implementations must embed UnimplementedQueryServer
+// for forward compatibility
+type QueryServer interface {
+	GetBlockByHeight(context.Context, *GetBlockByHeightRequest) (*Block, error)
+	GetBlockByHash(context.Context, *GetBlockByHashRequest) (*Block, error)
+	GetTransaction(context.Context, *GetTransactionRequest) (*Transaction, error)
+	GetBalance(context.Context, *GetBalanceRequest) (*Balance, error)
+	ListUTXOs(context.Context, *ListUTXOsRequest) (*UTXOList, error)
+	GetTransactionStatus(context.Context, *GetTransactionStatusRequest) (*TransactionStatus, error)
+	SubscribeBlocks(*SubscribeBlocksRequest, Query_SubscribeBlocksServer) error
+	SubscribeMempool(*SubscribeMempoolRequest, Query_SubscribeMempoolServer) error
+	SubscribeAddress(*SubscribeAddressRequest, Query_SubscribeAddressServer) error
+	mustEmbedUnimplementedQueryServer()
+}
+
+// UnimplementedQueryServer must be embedded to have forward compatible implementations.
+type UnimplementedQueryServer struct {
+}
+
+func (UnimplementedQueryServer) GetBlockByHeight(context.Context, *GetBlockByHeightRequest) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockByHeight not implemented")
+}
+func (UnimplementedQueryServer) GetBlockByHash(context.Context, *GetBlockByHashRequest) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockByHash not implemented")
+}
+func (UnimplementedQueryServer) GetTransaction(context.Context, *GetTransactionRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
+}
+func (UnimplementedQueryServer) GetBalance(context.Context, *GetBalanceRequest) (*Balance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedQueryServer) ListUTXOs(context.Context, *ListUTXOsRequest) (*UTXOList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUTXOs not implemented")
+}
+func (UnimplementedQueryServer) GetTransactionStatus(context.Context, *GetTransactionStatusRequest) (*TransactionStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransactionStatus not implemented")
+}
+func (UnimplementedQueryServer) SubscribeBlocks(*SubscribeBlocksRequest, Query_SubscribeBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeBlocks not implemented")
+}
+func (UnimplementedQueryServer) SubscribeMempool(*SubscribeMempoolRequest, Query_SubscribeMempoolServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeMempool not implemented")
+}
+func (UnimplementedQueryServer) SubscribeAddress(*SubscribeAddressRequest, Query_SubscribeAddressServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeAddress not implemented")
+}
+func (UnimplementedQueryServer) mustEmbedUnimplementedQueryServer() {}
+
+// UnsafeQueryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QueryServer will
+// result in compilation errors.
+type UnsafeQueryServer interface {
+	mustEmbedUnimplementedQueryServer()
+}
+
+func RegisterQueryServer(s grpc.ServiceRegistrar, srv QueryServer) {
+	s.RegisterService(&Query_ServiceDesc, srv)
+}
+
+func _Query_GetBlockByHeight_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlockByHeightRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetBlockByHeight(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetBlockByHeight",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetBlockByHeight(ctx, req.(*GetBlockByHeightRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetBlockByHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlockByHashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetBlockByHash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetBlockByHash",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetBlockByHash(ctx, req.(*GetBlockByHashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetTransaction(ctx, req.(*GetTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_ListUTXOs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUTXOsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).ListUTXOs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/ListUTXOs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).ListUTXOs(ctx, req.(*ListUTXOsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetTransactionStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetTransactionStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetTransactionStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetTransactionStatus(ctx, req.(*GetTransactionStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_SubscribeBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeBlocksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServer).SubscribeBlocks(m, &querySubscribeBlocksServer{stream})
+}
+
+type Query_SubscribeBlocksServer interface {
+	Send(*Block) error
+	grpc.ServerStream
+}
+
+type querySubscribeBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *querySubscribeBlocksServer) Send(m *Block) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Query_SubscribeMempool_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeMempoolRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServer).SubscribeMempool(m, &querySubscribeMempoolServer{stream})
+}
+
+type Query_SubscribeMempoolServer interface {
+	Send(*Transaction) error
+	grpc.ServerStream
+}
+
+type querySubscribeMempoolServer struct {
+	grpc.ServerStream
+}
+
+func (x *querySubscribeMempoolServer) Send(m *Transaction) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Query_SubscribeAddress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeAddressRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServer).SubscribeAddress(m, &querySubscribeAddressServer{stream})
+}
+
+type Query_SubscribeAddressServer interface {
+	Send(*AddressEvent) error
+	grpc.ServerStream
+}
+
+type querySubscribeAddressServer struct {
+	grpc.ServerStream
+}
+
+func (x *querySubscribeAddressServer) Send(m *AddressEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Query_ServiceDesc is the grpc.ServiceDesc for Query service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Query_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "Query",
+	HandlerType: (*QueryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBlockByHeight",
+			Handler:    _Query_GetBlockByHeight_Handler,
+		},
+		{
+			MethodName: "GetBlockByHash",
+			Handler:    _Query_GetBlockByHash_Handler,
+		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _Query_GetTransaction_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _Query_GetBalance_Handler,
+		},
+		{
+			MethodName: "ListUTXOs",
+			Handler:    _Query_ListUTXOs_Handler,
+		},
+		{
+			MethodName: "GetTransactionStatus",
+			Handler:    _Query_GetTransactionStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeBlocks",
+			Handler:       _Query_SubscribeBlocks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeMempool",
+			Handler:       _Query_SubscribeMempool_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeAddress",
+			Handler:       _Query_SubscribeAddress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/types.proto",
+}
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminClient interface {
+	ListBannedPeers(ctx context.Context, in *ListBannedPeersRequest, opts ...grpc.CallOption) (*BannedPeerList, error)
+	UnbanPeer(ctx context.Context, in *UnbanPeerRequest, opts ...grpc.CallOption) (*Ack, error)
+	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*Metrics, error)
+	ListPeers(ctx context.Context, in *ListPeersRequest, opts ...grpc.CallOption) (*PeerList, error)
+	ConnectPeer(ctx context.Context, in *ConnectPeerRequest, opts ...grpc.CallOption) (*PeerInfo, error)
+	DisconnectPeer(ctx context.Context, in *DisconnectPeerRequest, opts ...grpc.CallOption) (*Ack, error)
+	GetMempool(ctx context.Context, in *GetMempoolRequest, opts ...grpc.CallOption) (*MempoolInfo, error)
+	GetChainTip(ctx context.Context, in *GetChainTipRequest, opts ...grpc.CallOption) (*ChainTip, error)
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*LogLevel, error)
+	PauseValidator(ctx context.Context, in *PauseValidatorRequest, opts ...grpc.CallOption) (*ValidatorStatus, error)
+	ResumeValidator(ctx context.Context, in *ResumeValidatorRequest, opts ...grpc.CallOption) (*ValidatorStatus, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) ListBannedPeers(ctx context.Context, in *ListBannedPeersRequest, opts ...grpc.CallOption) (*BannedPeerList, error) {
+	out := new(BannedPeerList)
+	err := c.cc.Invoke(ctx, "/Admin/ListBannedPeers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) UnbanPeer(ctx context.Context, in *UnbanPeerRequest, opts ...grpc.CallOption) (*Ack, error) {
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, "/Admin/UnbanPeer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*Metrics, error) {
+	out := new(Metrics)
+	err := c.cc.Invoke(ctx, "/Admin/GetMetrics", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ListPeers(ctx context.Context, in *ListPeersRequest, opts ...grpc.CallOption) (*PeerList, error) {
+	out := new(PeerList)
+	err := c.cc.Invoke(ctx, "/Admin/ListPeers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ConnectPeer(ctx context.Context, in *ConnectPeerRequest, opts ...grpc.CallOption) (*PeerInfo, error) {
+	out := new(PeerInfo)
+	err := c.cc.Invoke(ctx, "/Admin/ConnectPeer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) DisconnectPeer(ctx context.Context, in *DisconnectPeerRequest, opts ...grpc.CallOption) (*Ack, error) {
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, "/Admin/DisconnectPeer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetMempool(ctx context.Context, in *GetMempoolRequest, opts ...grpc.CallOption) (*MempoolInfo, error) {
+	out := new(MempoolInfo)
+	err := c.cc.Invoke(ctx, "/Admin/GetMempool", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetChainTip(ctx context.Context, in *GetChainTipRequest, opts ...grpc.CallOption) (*ChainTip, error) {
+	out := new(ChainTip)
+	err := c.cc.Invoke(ctx, "/Admin/GetChainTip", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*LogLevel, error) {
+	out := new(LogLevel)
+	err := c.cc.Invoke(ctx, "/Admin/SetLogLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) PauseValidator(ctx context.Context, in *PauseValidatorRequest, opts ...grpc.CallOption) (*ValidatorStatus, error) {
+	out := new(ValidatorStatus)
+	err := c.cc.Invoke(ctx, "/Admin/PauseValidator", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ResumeValidator(ctx context.Context, in *ResumeValidatorRequest, opts ...grpc.CallOption) (*ValidatorStatus, error) {
+	out := new(ValidatorStatus)
+	err := c.cc.Invoke(ctx, "/Admin/ResumeValidator", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility
+type AdminServer interface {
+	ListBannedPeers(context.Context, *ListBannedPeersRequest) (*BannedPeerList, error)
+	UnbanPeer(context.Context, *UnbanPeerRequest) (*Ack, error)
+	GetMetrics(context.Context, *GetMetricsRequest) (*Metrics, error)
+	ListPeers(context.Context, *ListPeersRequest) (*PeerList, error)
+	ConnectPeer(context.Context, *ConnectPeerRequest) (*PeerInfo, error)
+	DisconnectPeer(context.Context, *DisconnectPeerRequest) (*Ack, error)
+	GetMempool(context.Context, *GetMempoolRequest) (*MempoolInfo, error)
+	GetChainTip(context.Context, *GetChainTipRequest) (*ChainTip, error)
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*LogLevel, error)
+	PauseValidator(context.Context, *PauseValidatorRequest) (*ValidatorStatus, error)
+	ResumeValidator(context.Context, *ResumeValidatorRequest) (*ValidatorStatus, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminServer struct {
+}
+
+func (UnimplementedAdminServer) ListBannedPeers(context.Context, *ListBannedPeersRequest) (*BannedPeerList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBannedPeers not implemented")
+}
+func (UnimplementedAdminServer) UnbanPeer(context.Context, *UnbanPeerRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnbanPeer not implemented")
+}
+func (UnimplementedAdminServer) GetMetrics(context.Context, *GetMetricsRequest) (*Metrics, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetrics not implemented")
+}
+func (UnimplementedAdminServer) ListPeers(context.Context, *ListPeersRequest) (*PeerList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeers not implemented")
+}
+func (UnimplementedAdminServer) ConnectPeer(context.Context, *ConnectPeerRequest) (*PeerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConnectPeer not implemented")
+}
+func (UnimplementedAdminServer) DisconnectPeer(context.Context, *DisconnectPeerRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectPeer not implemented")
+}
+func (UnimplementedAdminServer) GetMempool(context.Context, *GetMempoolRequest) (*MempoolInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMempool not implemented")
+}
+func (UnimplementedAdminServer) GetChainTip(context.Context, *GetChainTipRequest) (*ChainTip, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChainTip not implemented")
+}
+func (UnimplementedAdminServer) SetLogLevel(context.Context, *SetLogLevelRequest) (*LogLevel, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+func (UnimplementedAdminServer) PauseValidator(context.Context, *PauseValidatorRequest) (*ValidatorStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseValidator not implemented")
+}
+func (UnimplementedAdminServer) ResumeValidator(context.Context, *ResumeValidatorRequest) (*ValidatorStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeValidator not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_ListBannedPeers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBannedPeersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListBannedPeers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/ListBannedPeers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListBannedPeers(ctx, req.(*ListBannedPeersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_UnbanPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnbanPeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).UnbanPeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/UnbanPeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).UnbanPeer(ctx, req.(*UnbanPeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/GetMetrics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetMetrics(ctx, req.(*GetMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ListPeers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPeersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListPeers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/ListPeers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListPeers(ctx, req.(*ListPeersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ConnectPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConnectPeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ConnectPeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/ConnectPeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ConnectPeer(ctx, req.(*ConnectPeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_DisconnectPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectPeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).DisconnectPeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/DisconnectPeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).DisconnectPeer(ctx, req.(*DisconnectPeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetMempool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMempoolRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetMempool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/GetMempool",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetMempool(ctx, req.(*GetMempoolRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetChainTip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChainTipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetChainTip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/GetChainTip",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetChainTip(ctx, req.(*GetChainTipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/SetLogLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_PauseValidator_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseValidatorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).PauseValidator(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/PauseValidator",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).PauseValidator(ctx, req.(*PauseValidatorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ResumeValidator_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeValidatorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ResumeValidator(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Admin/ResumeValidator",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ResumeValidator(ctx, req.(*ResumeValidatorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListBannedPeers",
+			Handler:    _Admin_ListBannedPeers_Handler,
+		},
+		{
+			MethodName: "UnbanPeer",
+			Handler:    _Admin_UnbanPeer_Handler,
+		},
+		{
+			MethodName: "GetMetrics",
+			Handler:    _Admin_GetMetrics_Handler,
+		},
+		{
+			MethodName: "ListPeers",
+			Handler:    _Admin_ListPeers_Handler,
+		},
+		{
+			MethodName: "ConnectPeer",
+			Handler:    _Admin_ConnectPeer_Handler,
+		},
+		{
+			MethodName: "DisconnectPeer",
+			Handler:    _Admin_DisconnectPeer_Handler,
+		},
+		{
+			MethodName: "GetMempool",
+			Handler:    _Admin_GetMempool_Handler,
+		},
+		{
+			MethodName: "GetChainTip",
+			Handler:    _Admin_GetChainTip_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _Admin_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "PauseValidator",
+			Handler:    _Admin_PauseValidator_Handler,
+		},
+		{
+			MethodName: "ResumeValidator",
+			Handler:    _Admin_ResumeValidator_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/types.proto",
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/node"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/wallet"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+/*
+Runs a local simulation: starts a validator on :3000 (with the HTTP gateway on :8080) and two nodes that connect to it,
+and keeps spending the genesis allocation until interrupted
+*/
+func runSim(args []string) error {
+	if err := newFlagSet("sim").Parse(args); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	nodes := []*node.Node{
+		makeNode(ctx, ":3000", []string{}, true), // creates a genesis node
+	}
+	time.Sleep(time.Second)
+	nodes = append(nodes, makeNode(ctx, ":4000", []string{":3000"}, false)) // creates a node that connects to the genesis node
+	time.Sleep(time.Second)
+	nodes = append(nodes, makeNode(ctx, ":6000", []string{":4000"}, false)) // creates a node that connects to the genesis node
+
+	sender := newTxSender(":3000")
+	for {
+		select {
+		case <-ctx.Done(): // stops all the nodes on interrupt
+			for _, n := range nodes {
+				n.Stop()
+			}
+			return nil
+		case <-time.After(time.Second):
+			if err := sender.makeTransaction(ctx); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+func makeNode(ctx context.Context, listenAddr string, bootstrapNodes []string, isValidator bool) *node.Node {
+	cfg := node.ServerConfig{
+		Version:        "Blocker-1",
+		ListenAddr:     listenAddr,
+		BootstrapNodes: bootstrapNodes,
+	}
+	if isValidator {
+		cfg.PrivateKey = crypto.GeneratePrivateKey()
+		cfg.HTTPListenAddr = ":8080" // JSON API of the genesis node
+	}
+	n := node.NewNode(cfg) // creates a new node
+	go func() {
+		if err := n.Start(ctx); err != nil { // starts the node
+			log.Fatal(err)
+		}
+	}()
+	return n
+}
+
+/*
+temporary: just to test gRPC calls
+
+Spends the genesis allocation sending a few coins to random addresses, one transaction per block:
+a new transaction is sent only after the previous one was confirmed, so its change can be spent
+*/
+type txSender struct {
+	client  proto.NodeClient
+	query   proto.QueryClient
+	privKey *crypto.PrivateKey
+	pending []byte // hash of the last transaction sent, while not confirmed
+}
+
+func newTxSender(addr string) *txSender {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &txSender{
+		client:  proto.NewNodeClient(conn),
+		query:   proto.NewQueryClient(conn),
+		privKey: crypto.NewPrivateKeyFromString(node.GenesisSeed),
+	}
+}
+
+func (s *txSender) makeTransaction(ctx context.Context) error {
+	if s.pending != nil {
+		status, err := s.query.GetTransactionStatus(ctx, &proto.GetTransactionStatusRequest{Hash: s.pending})
+		if err != nil {
+			return err
+		}
+		if status.Status == proto.TxStatus_TX_STATUS_PENDING {
+			return nil
+		}
+		log.Printf("tx %s: %s (block %d) %s", hex.EncodeToString(s.pending), status.Status, status.BlockHeight, status.Error)
+		s.pending = nil
+	}
+	var (
+		to     = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		amount = rand.Int63n(10) + 1
+	)
+	// the genesis allocation is in the legacy address of the key: the wallet spends it and keeps the change in the hashed one
+	tx, err := wallet.New(s.query, s.privKey).Transfer(ctx, to, amount, 0, wallet.LargestFirst)
+	if err != nil {
+		return err
+	}
+
+	ack, err := s.client.HandleTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if !ack.Accepted {
+		return fmt.Errorf("tx %s rejected: %s", hex.EncodeToString(ack.Hash), ack.Error)
+	}
+	s.pending = ack.Hash
+	return nil
+}
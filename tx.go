@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/wallet"
+)
+
+const statusPollInterval = time.Second
+
+/*
+Sends coins from the address of the key to another address:
+ 1. Lists the unspent outputs of the address in the node
+ 2. Selects the outputs to spend and builds the signed transaction, with the change back to the address
+ 3. Sends the transaction and, with --wait, waits until it is confirmed or rejected
+*/
+func runTxSend(args []string) error {
+	var (
+		fs       = newFlagSet("tx send")
+		addr     = fs.String("node", ":3000", "address of the node")
+		keyFile  = fs.String("key", "", "key file (plain or encrypted) of the sender")
+		passFile = fs.String("passphrase-file", "", "file with the passphrase of an encrypted key")
+		to       = fs.String("to", "", "address that receives the coins")
+		amount   = fs.Int64("amount", 0, "amount to send")
+		feeRate  = fs.Int64("fee-rate", 0, "fee paid per byte of the transaction")
+		largest  = fs.Bool("largest-first", false, "spends the largest outputs first instead of looking for outputs without change")
+		wait     = fs.Bool("wait", false, "waits until the transaction is confirmed or rejected")
+		tlsFlags = addTLSFlags(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return fmt.Errorf("the key file is required")
+	}
+	key, err := loadKey(*keyFile, *passFile)
+	if err != nil {
+		return err
+	}
+	toAddress, err := crypto.ParseAddress(*to)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dial(*addr, tlsFlags)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var (
+		ctx      = context.Background()
+		query    = proto.NewQueryClient(conn)
+		client   = proto.NewNodeClient(conn)
+		strategy = wallet.BranchAndBound
+	)
+	if *largest {
+		strategy = wallet.LargestFirst
+	}
+	tx, err := wallet.New(query, key).Transfer(ctx, toAddress.Bytes(), *amount, *feeRate, strategy)
+	if err != nil {
+		return err
+	}
+	ack, err := client.HandleTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if !ack.Accepted {
+		return fmt.Errorf("transaction %s rejected: %s", hex.EncodeToString(ack.Hash), ack.Error)
+	}
+	fmt.Println("transaction:", hex.EncodeToString(ack.Hash))
+	if !*wait {
+		return nil
+	}
+	for {
+		status, err := query.GetTransactionStatus(ctx, &proto.GetTransactionStatusRequest{Hash: ack.Hash})
+		if err != nil {
+			return err
+		}
+		switch status.Status {
+		case proto.TxStatus_TX_STATUS_CONFIRMED:
+			fmt.Printf("confirmed in block %d (%s)\n", status.BlockHeight, hex.EncodeToString(status.BlockHash))
+			return nil
+		case proto.TxStatus_TX_STATUS_REJECTED:
+			return fmt.Errorf("transaction rejected: %s", status.Error)
+		}
+		time.Sleep(statusPollInterval)
+	}
+}
@@ -58,13 +58,7 @@ func SignBlock(pk *crypto.PrivateKey, b *proto.Block) *crypto.Signature {
 		2. Transform de block header in []byte
 		3. Sign it with private key
 	*/
-	if len(b.Transactions) > 0 {
-		tree, err := GetMerkleTree(b)
-		if err != nil {
-			panic(err)
-		}
-		b.Header.RootHash = tree.MerkleRoot()
-	}
+	SetRootHash(b)
 	hash := HashBlock(b)              // block hashed in a [32]byte
 	sig := pk.Sign(hash)              // returns a signature (64 bytes)
 	b.PublicKey = pk.Public().Bytes() // public key used to unsign the pk.Sign(hash)
@@ -72,11 +66,26 @@ func SignBlock(pk *crypto.PrivateKey, b *proto.Block) *crypto.Signature {
 	return sig
 }
 
+// Sets the merkle root of the transactions in the header of the block (empty if it has no transactions)
+func SetRootHash(b *proto.Block) {
+	if len(b.Transactions) == 0 {
+		b.Header.RootHash = nil
+		return
+	}
+	tree, err := GetMerkleTree(b)
+	if err != nil {
+		panic(err)
+	}
+	b.Header.RootHash = tree.MerkleRoot()
+}
+
 func VerifyBlock(b *proto.Block) bool {
 	if len(b.Transactions) > 0 {
 		if !VerifyRootHash(b) {
 			return false
 		}
+	} else if len(b.Header.RootHash) > 0 { // the transactions were removed from the block after it was signed
+		return false
 	}
 	if len(b.PublicKey) != crypto.PubKeyLen {
 		return false
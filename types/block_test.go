@@ -21,6 +21,11 @@ func TestCalculateRootHash(t *testing.T) {
 	SignBlock(privKey, block)
 	assert.True(t, VerifyRootHash(block))
 	assert.Equal(t, 32, len(block.Header.RootHash))
+	assert.True(t, VerifyBlock(block))
+
+	// the block keeps the same hash without its transactions, but the root no longer matches them
+	block.Transactions = nil
+	assert.False(t, VerifyBlock(block))
 }
 
 func TestHashBlock(t *testing.T) {
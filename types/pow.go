@@ -0,0 +1,58 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/CaiqueRibeiro/blocker/proto"
+)
+
+var maxHash = new(big.Int).Lsh(big.NewInt(1), 256) // 2^256, one more than the biggest hash
+
+/*
+Target of the bits in compact format, as in Bitcoin: the first byte is the exponent (size of the target in bytes)
+and the other 3 are the mantissa, so the target is mantissa * 256^(exponent-3).
+Bits with the sign bit of the mantissa set (negative targets) have target zero, which no hash meets
+*/
+func CompactToTarget(bits uint32) *big.Int {
+	var (
+		exponent = uint(bits >> 24)
+		mantissa = big.NewInt(int64(bits & 0x007fffff))
+	)
+	if bits&0x00800000 != 0 {
+		return new(big.Int)
+	}
+	if exponent <= 3 {
+		return mantissa.Rsh(mantissa, 8*(3-exponent))
+	}
+	return mantissa.Lsh(mantissa, 8*(exponent-3))
+}
+
+// Compact format of the target, truncated to the 3 most significant bytes
+func TargetToCompact(target *big.Int) uint32 {
+	var (
+		size     = uint((target.BitLen() + 7) / 8)
+		mantissa uint32
+	)
+	if size <= 3 {
+		mantissa = uint32(target.Uint64() << (8 * (3 - size)))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(target, 8*(size-3)).Uint64())
+	}
+	if mantissa&0x00800000 != 0 { // the sign bit moves to the next byte
+		mantissa >>= 8
+		size++
+	}
+	return uint32(size)<<24 | mantissa
+}
+
+// Expected number of hashes to find one that meets the target of the bits
+func Work(bits uint32) *big.Int {
+	target := CompactToTarget(bits)
+	return new(big.Int).Div(maxHash, target.Add(target, big.NewInt(1)))
+}
+
+// Verifies if the hash of the header is lower or equal to the target of its bits
+func CheckProofOfWork(h *proto.Header) bool {
+	target := CompactToTarget(h.Bits)
+	return target.Sign() > 0 && new(big.Int).SetBytes(HashHeader(h)).Cmp(target) <= 0
+}
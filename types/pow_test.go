@@ -0,0 +1,41 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactTarget(t *testing.T) {
+	// bits of the first Bitcoin block
+	target := CompactToTarget(0x1d00ffff)
+	expected, _ := new(big.Int).SetString("00000000ffff0000000000000000000000000000000000000000000000000000", 16)
+	assert.Equal(t, expected, target)
+	assert.Equal(t, uint32(0x1d00ffff), TargetToCompact(target))
+
+	for _, bits := range []uint32{0x207fffff, 0x1f00ffff, 0x1b0404cb, 0x03123456, 0x02008000} {
+		assert.Equal(t, bits, TargetToCompact(CompactToTarget(bits)), "%08x", bits)
+	}
+	// the sign bit of the mantissa moves to the next byte
+	assert.Equal(t, uint32(0x02008000), TargetToCompact(big.NewInt(0x80)))
+	assert.Zero(t, CompactToTarget(0x1d800000).Sign())
+}
+
+func TestWork(t *testing.T) {
+	assert.Equal(t, big.NewInt(0x0100010001), Work(0x1d00ffff))
+	assert.Equal(t, 1, Work(0x1d00ffff).Cmp(Work(0x1e00ffff)))
+}
+
+func TestCheckProofOfWork(t *testing.T) {
+	header := util.RandomBlock().Header
+	header.Bits = 0x207fffff // half of the hashes meet the target
+	for !CheckProofOfWork(header) {
+		header.Nonce++
+	}
+	header.Bits = 0x03000001
+	assert.False(t, CheckProofOfWork(header))
+	header.Bits = 0
+	assert.False(t, CheckProofOfWork(header))
+}
@@ -17,12 +17,62 @@ func HashTransaction(tx *proto.Transaction) []byte {
 	return hash[:]
 }
 
+/*
+Hashes the transaction without the signatures of its inputs and of its validator operation.
+This is the message signed by each input (and validator key), so they can be signed in any order
+*/
+func HashUnsignedTransaction(tx *proto.Transaction) []byte {
+	unsigned := pb.Clone(tx).(*proto.Transaction)
+	for _, input := range unsigned.Inputs {
+		input.Signature = nil
+	}
+	if op := unsigned.ValidatorOp; op != nil {
+		op.Signature = nil
+		op.NewSignature = nil
+	}
+	return HashTransaction(unsigned)
+}
+
+// Signs the validator operation of the transaction with the validator key and, when rotating, with the new key
+func SignValidatorOp(tx *proto.Transaction, key, newKey *crypto.PrivateKey) {
+	hash := HashUnsignedTransaction(tx)
+	tx.ValidatorOp.Signature = key.Sign(hash).Bytes()
+	if newKey != nil {
+		tx.ValidatorOp.NewSignature = newKey.Sign(hash).Bytes()
+	}
+}
+
+// Verifies the signature of the validator key of the operation and, when rotating, of the new key (owner of the new key)
+func VerifyValidatorOp(tx *proto.Transaction) bool {
+	op := tx.ValidatorOp
+	if op == nil {
+		return false
+	}
+	hash := HashUnsignedTransaction(tx)
+	if !verifySignature(op.PublicKey, op.Signature, hash) {
+		return false
+	}
+	return op.Type != proto.ValidatorOpType_VALIDATOR_OP_ROTATE || verifySignature(op.NewPublicKey, op.NewSignature, hash)
+}
+
+func verifySignature(pubKey, sig, msg []byte) bool {
+	if len(pubKey) != crypto.PubKeyLen || len(sig) != crypto.SignatureLen {
+		return false
+	}
+	return crypto.SignatureFromBytes(sig).Verify(crypto.PublicKeyFromBytes(pubKey), msg)
+}
+
 func SignTransaction(pk *crypto.PrivateKey, tx *proto.Transaction) *crypto.Signature {
-	ht := HashTransaction(tx)
+	ht := HashUnsignedTransaction(tx)
 	return pk.Sign(ht)
 }
 
 func VerifyTransaction(tx *proto.Transaction) bool {
+	/*
+		The signatures are removed from a copy of the transaction, because when the inputs were signed
+		there were no signatures in them yet (the transaction itself is not changed, so it can be shared)
+	*/
+	hash := HashUnsignedTransaction(tx)
 	for _, input := range tx.Inputs {
 		if len(input.Signature) == 0 {
 			panic("the transaction has no signature")
@@ -31,16 +81,9 @@ func VerifyTransaction(tx *proto.Transaction) bool {
 			sig    = crypto.SignatureFromBytes(input.Signature)
 			pubKey = crypto.PublicKeyFromBytes(input.PublicKey)
 		)
-		/*
-			Removes the signature to not break Verify, because when transaction input was signed,
-			there was not signature in input yet
-		*/
-		tempSig := input.Signature
-		input.Signature = nil
-		if !sig.Verify(pubKey, HashTransaction(tx)) {
+		if !sig.Verify(pubKey, hash) {
 			return false
 		}
-		input.Signature = tempSig
 	}
 	return true
 }
@@ -43,3 +43,62 @@ func TestNewtransaction(t *testing.T) {
 
 	assert.True(t, VerifyTransaction(tx)) // verify if transaction was signed properly in flow
 }
+
+func TestVerifyTransactionMultipleInputs(t *testing.T) {
+	var (
+		privKey1 = crypto.GeneratePrivateKey()
+		privKey2 = crypto.GeneratePrivateKey()
+		tx       = &proto.Transaction{
+			Version: 1,
+			Inputs: []*proto.TxInput{
+				{PrevTxHash: util.RandomHash(), PublicKey: privKey1.Public().Bytes()},
+				{PrevTxHash: util.RandomHash(), PublicKey: privKey2.Public().Bytes()},
+			},
+			Outputs: []*proto.TxOutput{
+				{Amount: 10, Address: privKey1.Public().Address().Bytes()},
+			},
+		}
+	)
+	// inputs can be signed in any order, each one over the transaction without signatures
+	tx.Inputs[1].Signature = SignTransaction(privKey2, tx).Bytes()
+	tx.Inputs[0].Signature = SignTransaction(privKey1, tx).Bytes()
+	assert.True(t, VerifyTransaction(tx))
+
+	// verifying does not change the transaction
+	sig := tx.Inputs[0].Signature
+	tx.Outputs[0].Amount = 20
+	assert.False(t, VerifyTransaction(tx))
+	assert.Equal(t, sig, tx.Inputs[0].Signature)
+}
+
+func TestSignValidatorOp(t *testing.T) {
+	var (
+		owner  = crypto.GeneratePrivateKey()
+		oldKey = crypto.GeneratePrivateKey()
+		newKey = crypto.GeneratePrivateKey()
+		tx     = &proto.Transaction{
+			Version: 1,
+			Inputs:  []*proto.TxInput{{PrevTxHash: util.RandomHash(), PublicKey: owner.Public().Bytes()}},
+			Outputs: []*proto.TxOutput{{Amount: 10, Address: owner.Public().Address().Bytes()}},
+			ValidatorOp: &proto.ValidatorOp{
+				Type:         proto.ValidatorOpType_VALIDATOR_OP_ROTATE,
+				PublicKey:    oldKey.Public().Bytes(),
+				NewPublicKey: newKey.Public().Bytes(),
+			},
+		}
+	)
+	// the inputs and the validator keys sign the same message
+	tx.Inputs[0].Signature = SignTransaction(owner, tx).Bytes()
+	SignValidatorOp(tx, oldKey, newKey)
+	assert.True(t, VerifyTransaction(tx))
+	assert.True(t, VerifyValidatorOp(tx))
+
+	// rotating requires the signature of the new key
+	sig := tx.ValidatorOp.NewSignature
+	tx.ValidatorOp.NewSignature = nil
+	assert.False(t, VerifyValidatorOp(tx))
+	tx.ValidatorOp.NewSignature = sig
+	tx.ValidatorOp.NewPublicKey = crypto.GeneratePrivateKey().Public().Bytes()
+	assert.False(t, VerifyValidatorOp(tx))
+	assert.False(t, VerifyTransaction(tx))
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"crypto/sha256"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Gets a version and hashes it in a sha256 [32]byte, ignoring its signature
+func HashVersion(v *proto.Version) []byte {
+	unsigned := pb.Clone(v).(*proto.Version)
+	unsigned.Signature = nil
+	b, err := pb.Marshal(unsigned)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.Sum256(b)
+	return hash[:]
+}
+
+/*
+Signs the version with the node identity key.
+The version must already carry the nonce (challenge) issued by the receiving node,
+so the signature can only be used once and proves the ownership of the key
+*/
+func SignVersion(pk *crypto.PrivateKey, v *proto.Version) *crypto.Signature {
+	v.PublicKey = pk.Public().Bytes()
+	sig := pk.Sign(HashVersion(v))
+	v.Signature = sig.Bytes()
+	return sig
+}
+
+func VerifyVersion(v *proto.Version) bool {
+	if len(v.PublicKey) != crypto.PubKeyLen {
+		return false
+	}
+	if len(v.Signature) != crypto.SignatureLen {
+		return false
+	}
+	var (
+		sig    = crypto.SignatureFromBytes(v.Signature)
+		pubKey = crypto.PublicKeyFromBytes(v.PublicKey)
+	)
+	return sig.Verify(pubKey, HashVersion(v))
+}
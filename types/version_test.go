@@ -0,0 +1,34 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerifyVersion(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		version = &proto.Version{
+			Version:    "blocker-0.1",
+			ListenAddr: ":3000",
+			Nonce:      util.RandomHash(),
+		}
+	)
+	sig := SignVersion(privKey, version)
+	assert.Equal(t, 64, len(sig.Bytes()))
+	assert.Equal(t, privKey.Public().Bytes(), version.PublicKey)
+	assert.True(t, VerifyVersion(version))
+
+	// a version claiming another listen address is not valid anymore
+	version.ListenAddr = ":4000"
+	assert.False(t, VerifyVersion(version))
+
+	// a version signed with a key that is not the claimed one is not valid
+	version.ListenAddr = ":3000"
+	version.PublicKey = crypto.GeneratePrivateKey().Public().Bytes()
+	assert.False(t, VerifyVersion(version))
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"crypto/sha256"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Hashes the vote without its signature, the message signed by the validator
+func HashVote(v *proto.Vote) []byte {
+	unsigned := &proto.Vote{
+		Type:      v.Type,
+		Height:    v.Height,
+		Round:     v.Round,
+		BlockHash: v.BlockHash,
+		PublicKey: v.PublicKey,
+	}
+	b, err := pb.Marshal(unsigned)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.Sum256(b)
+	return hash[:]
+}
+
+// Sets the public key of the validator in the vote and signs it
+func SignVote(pk *crypto.PrivateKey, v *proto.Vote) {
+	v.PublicKey = pk.Public().Bytes()
+	v.Signature = pk.Sign(HashVote(v)).Bytes()
+}
+
+func VerifyVote(v *proto.Vote) bool {
+	if len(v.PublicKey) != crypto.PubKeyLen || len(v.Signature) != crypto.SignatureLen {
+		return false
+	}
+	var (
+		sig    = crypto.SignatureFromBytes(v.Signature)
+		pubKey = crypto.PublicKeyFromBytes(v.PublicKey)
+	)
+	return sig.Verify(pubKey, HashVote(v))
+}
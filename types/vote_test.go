@@ -0,0 +1,37 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVote(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		vote    = &proto.Vote{
+			Type:      proto.VoteType_VOTE_TYPE_PRECOMMIT,
+			Height:    3,
+			Round:     1,
+			BlockHash: util.RandomHash(),
+		}
+	)
+	SignVote(privKey, vote)
+	assert.Equal(t, privKey.Public().Bytes(), vote.PublicKey)
+	assert.True(t, VerifyVote(vote))
+
+	// the signature covers the type, height, round and block of the vote
+	vote.Type = proto.VoteType_VOTE_TYPE_PREVOTE
+	assert.False(t, VerifyVote(vote))
+	vote.Type = proto.VoteType_VOTE_TYPE_PRECOMMIT
+	vote.Round = 2
+	assert.False(t, VerifyVote(vote))
+	vote.Round = 1
+	vote.PublicKey = crypto.GeneratePrivateKey().Public().Bytes()
+	assert.False(t, VerifyVote(vote))
+	vote.PublicKey = nil
+	assert.False(t, VerifyVote(vote))
+}
@@ -0,0 +1,166 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+)
+
+// Maximum number of combinations tried by the branch and bound search
+const bnbMaxTries = 100000
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Strategy of the coin selection
+type Strategy int
+
+const (
+	// Looks for a set of coins paying the amount and fee without change, falling back to LargestFirst
+	BranchAndBound Strategy = iota
+	// Spends the largest coins first, sending the change back to the wallet
+	LargestFirst
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case BranchAndBound:
+		return "branch-and-bound"
+	case LargestFirst:
+		return "largest-first"
+	default:
+		return fmt.Sprintf("strategy(%d)", int(s))
+	}
+}
+
+// Unspent output owned by a key of the wallet
+type Coin struct {
+	UTXO *proto.UTXO
+	key  *crypto.PrivateKey
+}
+
+// Coins chosen to fund a transaction, with the resulting change (0 when there is no change output) and fee
+type Selection struct {
+	Coins  []Coin
+	Change int64
+	Fee    int64
+}
+
+/*
+Selects coins paying amount plus the fee of the transaction, spending coins with a fee higher than their value is never worth it:
+ 1. With BranchAndBound, searches for coins whose value covers the amount and fee with an excess
+    lower than the cost of making change, so the excess goes to the fee and no change output is created
+ 2. Otherwise (or with LargestFirst), spends the largest coins until the amount and fee are covered,
+    adding a change output unless the change is not worth its cost
+*/
+func selectCoins(coins []Coin, amount int64, fees feeCosts, strategy Strategy) (*Selection, error) {
+	candidates := make([]Coin, 0, len(coins))
+	for _, coin := range coins {
+		if coin.UTXO.Amount > fees.input {
+			candidates = append(candidates, coin)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].UTXO.Amount > candidates[j].UTXO.Amount
+	})
+
+	target := amount + fees.base
+	if strategy == BranchAndBound {
+		if selected := branchAndBound(candidates, target, fees); selected != nil {
+			return newSelection(selected, amount, 0), nil
+		}
+	}
+	return largestFirst(candidates, amount, fees)
+}
+
+func largestFirst(coins []Coin, amount int64, fees feeCosts) (*Selection, error) {
+	var (
+		target = amount + fees.base
+		value  int64 // value of the selected coins minus the fee of their inputs
+	)
+	for i, coin := range coins {
+		value += effectiveValue(coin, fees)
+		if value < target {
+			continue
+		}
+		var change int64
+		if excess := value - target; excess > fees.costOfChange() {
+			change = excess - fees.change
+		}
+		return newSelection(coins[:i+1], amount, change), nil
+	}
+	return nil, fmt.Errorf("%w: %d available, %d required", ErrInsufficientFunds, value, target)
+}
+
+/*
+Depth first search of the coins (sorted from largest to smallest) whose effective value is between
+target and target+costOfChange, including or excluding each coin in turn.
+Branches that overshoot, or can't reach the target with the remaining coins, are cut.
+Returns the set with the smallest excess, or nil if there is none
+*/
+func branchAndBound(coins []Coin, target int64, fees feeCosts) []Coin {
+	var (
+		upper     = target + fees.costOfChange()
+		remaining int64
+		tries     int
+		selected  []int
+		best      []int
+		bestWaste int64 = -1
+	)
+	for _, coin := range coins {
+		remaining += effectiveValue(coin, fees)
+	}
+
+	var search func(i int, value, remaining int64)
+	search = func(i int, value, remaining int64) {
+		tries++
+		switch {
+		case tries > bnbMaxTries || bestWaste == 0:
+			return
+		case value > upper:
+			return
+		case value >= target:
+			if waste := value - target; bestWaste < 0 || waste < bestWaste {
+				bestWaste = waste
+				best = append(best[:0], selected...)
+			}
+			return
+		case i == len(coins) || value+remaining < target:
+			return
+		}
+		v := effectiveValue(coins[i], fees)
+		selected = append(selected, i)
+		search(i+1, value+v, remaining-v)
+		selected = selected[:len(selected)-1]
+		search(i+1, value, remaining-v)
+	}
+	search(0, 0, remaining)
+
+	if best == nil {
+		return nil
+	}
+	result := make([]Coin, len(best))
+	for i, idx := range best {
+		result[i] = coins[idx]
+	}
+	return result
+}
+
+// Value of a coin minus the fee of spending it
+func effectiveValue(coin Coin, fees feeCosts) int64 {
+	return coin.UTXO.Amount - fees.input
+}
+
+func newSelection(coins []Coin, amount, change int64) *Selection {
+	var total int64
+	for _, coin := range coins {
+		total += coin.UTXO.Amount
+	}
+	return &Selection{
+		Coins:  append([]Coin{}, coins...),
+		Change: change,
+		Fee:    total - amount - change,
+	}
+}
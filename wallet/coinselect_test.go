@@ -0,0 +1,72 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func amounts(coins []Coin) []int64 {
+	var values []int64
+	for _, coin := range coins {
+		values = append(values, coin.UTXO.Amount)
+	}
+	return values
+}
+
+func TestSelectCoins(t *testing.T) {
+	var (
+		key   = crypto.GeneratePrivateKey()
+		coins = testCoins(key, 30, 50, 100, 7)
+		fees  = feeCosts{}
+	)
+
+	// exact match without change
+	selection, err := selectCoins(coins, 87, fees, BranchAndBound)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{50, 30, 7}, amounts(selection.Coins))
+	assert.Zero(t, selection.Change)
+	assert.Zero(t, selection.Fee)
+
+	// no exact match: falls back to the largest coins
+	selection, err = selectCoins(coins, 120, fees, BranchAndBound)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{100, 50}, amounts(selection.Coins))
+	assert.Equal(t, int64(30), selection.Change)
+
+	selection, err = selectCoins(coins, 80, fees, LargestFirst)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{100}, amounts(selection.Coins))
+	assert.Equal(t, int64(20), selection.Change)
+
+	_, err = selectCoins(coins, 188, fees, BranchAndBound)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestSelectCoinsWithFees(t *testing.T) {
+	var (
+		key   = crypto.GeneratePrivateKey()
+		fees  = feeCosts{base: 10, input: 5, change: 4}
+		coins = testCoins(key, 3, 55, 100, 40)
+	)
+
+	// 55+40 pays 70 plus the fee (10 + 2*5 = 20) with an excess of 5, lower than the cost of change (9)
+	selection, err := selectCoins(coins, 70, fees, BranchAndBound)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{55, 40}, amounts(selection.Coins))
+	assert.Zero(t, selection.Change)
+	assert.Equal(t, int64(25), selection.Fee)
+
+	// 100 pays 70 plus 15 of fee with 15 of excess: 11 of change, 4 paying the change output
+	selection, err = selectCoins(coins, 70, fees, LargestFirst)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{100}, amounts(selection.Coins))
+	assert.Equal(t, int64(11), selection.Change)
+	assert.Equal(t, int64(19), selection.Fee)
+
+	// the coin of 3 costs more to spend than it is worth: the other coins are worth 180 after their fees
+	_, err = selectCoins(coins, 171, fees, LargestFirst)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+}
@@ -0,0 +1,59 @@
+package wallet
+
+import (
+	"math"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+/*
+Encoded size (upper bound) of a transaction spending nInputs outputs to the given outputs.
+Inputs are measured signed and with the largest output index, so the estimate never falls short
+*/
+func EstimateSize(nInputs int, outputs []*proto.TxOutput) int {
+	tx := &proto.Transaction{
+		Version: 1,
+		Outputs: outputs,
+	}
+	for i := 0; i < nInputs; i++ {
+		tx.Inputs = append(tx.Inputs, &proto.TxInput{
+			PrevTxHash:   make([]byte, 32),
+			PrevOutIndex: math.MaxUint32,
+			PublicKey:    make([]byte, crypto.PubKeyLen),
+			Signature:    make([]byte, crypto.SignatureLen),
+		})
+	}
+	return pb.Size(tx)
+}
+
+// Fee of a transaction spending nInputs outputs, at feeRate per byte
+func EstimateFee(nInputs int, outputs []*proto.TxOutput, feeRate int64) int64 {
+	return int64(EstimateSize(nInputs, outputs)) * feeRate
+}
+
+// Fees of the parts of a transaction, used by the coin selection
+type feeCosts struct {
+	base   int64 // transaction with the payment outputs and no inputs
+	input  int64 // each input
+	change int64 // the change output
+}
+
+func newFeeCosts(outputs []*proto.TxOutput, change crypto.Address, feeRate int64) feeCosts {
+	withChange := append(append([]*proto.TxOutput{}, outputs...), &proto.TxOutput{
+		Amount:  math.MaxInt64,
+		Address: change.Bytes(),
+	})
+	base := EstimateFee(0, outputs, feeRate)
+	return feeCosts{
+		base:   base,
+		input:  EstimateFee(1, nil, feeRate) - EstimateFee(0, nil, feeRate),
+		change: EstimateFee(0, withChange, feeRate) - base,
+	}
+}
+
+// Cost of making change: creating the change output now plus spending it later
+func (f feeCosts) costOfChange() int64 {
+	return f.change + f.input
+}
@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+)
+
+const txVersion = 1
+
+/*
+Set of keys whose coins are spent together.
+The coins are queried from a node, and the change of every transaction goes back to the first key
+*/
+type Wallet struct {
+	keys  []*crypto.PrivateKey
+	query proto.QueryClient
+}
+
+func New(query proto.QueryClient, keys ...*crypto.PrivateKey) *Wallet {
+	return &Wallet{
+		keys:  keys,
+		query: query,
+	}
+}
+
+func (w *Wallet) AddKey(key *crypto.PrivateKey) {
+	w.keys = append(w.keys, key)
+}
+
+func (w *Wallet) Addresses() []crypto.Address {
+	addresses := make([]crypto.Address, len(w.keys))
+	for i, key := range w.keys {
+		addresses[i] = key.Public().Address()
+	}
+	return addresses
+}
+
+// Address that receives the change of the transactions
+func (w *Wallet) ChangeAddress() crypto.Address {
+	return w.keys[0].Public().Address()
+}
+
+/*
+Unspent outputs of every key of the wallet, including the outputs of their legacy addresses
+(spending them moves the coins to the hashed address of the change)
+*/
+func (w *Wallet) Coins(ctx context.Context) ([]Coin, error) {
+	var coins []Coin
+	for _, key := range w.keys {
+		for _, address := range []crypto.Address{key.Public().Address(), key.Public().LegacyAddress()} {
+			list, err := w.query.ListUTXOs(ctx, &proto.ListUTXOsRequest{Address: address.Bytes()})
+			if err != nil {
+				return nil, err
+			}
+			for _, utxo := range list.Utxos {
+				coins = append(coins, Coin{UTXO: utxo, key: key})
+			}
+		}
+	}
+	return coins, nil
+}
+
+func (w *Wallet) Balance(ctx context.Context) (int64, error) {
+	coins, err := w.Coins(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var balance int64
+	for _, coin := range coins {
+		balance += coin.UTXO.Amount
+	}
+	return balance, nil
+}
+
+// Builds a signed transaction sending amount to the address, paying feeRate per byte
+func (w *Wallet) Transfer(ctx context.Context, to []byte, amount, feeRate int64, strategy Strategy) (*proto.Transaction, error) {
+	if len(w.keys) == 0 {
+		return nil, fmt.Errorf("the wallet has no keys")
+	}
+	coins, err := w.Coins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	outputs := []*proto.TxOutput{
+		{
+			Amount:  amount,
+			Address: to,
+		},
+	}
+	tx, _, err := BuildTransaction(coins, outputs, w.ChangeAddress(), feeRate, strategy)
+	return tx, err
+}
+
+/*
+Builds a signed transaction paying the outputs with the coins:
+ 1. Selects the coins covering the outputs and the fee estimated at feeRate per byte
+ 2. Adds the change output back to the change address, if there is change
+ 3. Signs every input with the key owning its coin
+*/
+func BuildTransaction(coins []Coin, outputs []*proto.TxOutput, change crypto.Address, feeRate int64, strategy Strategy) (*proto.Transaction, *Selection, error) {
+	if len(outputs) == 0 {
+		return nil, nil, fmt.Errorf("the transaction has no outputs")
+	}
+	if feeRate < 0 {
+		return nil, nil, fmt.Errorf("the fee rate cannot be negative")
+	}
+	var amount int64
+	for _, output := range outputs {
+		if output.Amount <= 0 {
+			return nil, nil, fmt.Errorf("the amount must be positive")
+		}
+		amount += output.Amount
+	}
+
+	selection, err := selectCoins(coins, amount, newFeeCosts(outputs, change, feeRate), strategy)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx := &proto.Transaction{
+		Version: txVersion,
+		Outputs: append([]*proto.TxOutput{}, outputs...),
+	}
+	for _, coin := range selection.Coins {
+		tx.Inputs = append(tx.Inputs, &proto.TxInput{
+			PrevTxHash:   coin.UTXO.TxHash,
+			PrevOutIndex: coin.UTXO.OutIndex,
+			PublicKey:    coin.key.Public().Bytes(),
+		})
+	}
+	if selection.Change > 0 {
+		tx.Outputs = append(tx.Outputs, &proto.TxOutput{
+			Amount:  selection.Change,
+			Address: change.Bytes(),
+		})
+	}
+	Sign(tx, selection.Coins)
+	return tx, selection, nil
+}
+
+// Signs the input i of the transaction with the key of coins[i]. Inputs are signed over the transaction without signatures
+func Sign(tx *proto.Transaction, coins []Coin) {
+	sigs := make(map[*crypto.PrivateKey][]byte)
+	for i, coin := range coins {
+		sig, ok := sigs[coin.key]
+		if !ok {
+			sig = types.SignTransaction(coin.key, tx).Bytes()
+			sigs[coin.key] = sig
+		}
+		tx.Inputs[i].Signature = sig
+	}
+}
@@ -0,0 +1,98 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CaiqueRibeiro/blocker/crypto"
+	"github.com/CaiqueRibeiro/blocker/proto"
+	"github.com/CaiqueRibeiro/blocker/types"
+	"github.com/CaiqueRibeiro/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Query client answering ListUTXOs from a map of address to outputs
+type fakeQuery struct {
+	proto.QueryClient
+	utxos map[string][]*proto.UTXO
+}
+
+func (q *fakeQuery) ListUTXOs(ctx context.Context, req *proto.ListUTXOsRequest, opts ...grpc.CallOption) (*proto.UTXOList, error) {
+	return &proto.UTXOList{Utxos: q.utxos[string(req.Address)]}, nil
+}
+
+func testCoins(key *crypto.PrivateKey, amounts ...int64) []Coin {
+	coins := make([]Coin, len(amounts))
+	for i, amount := range amounts {
+		coins[i] = Coin{
+			UTXO: &proto.UTXO{TxHash: util.RandomHash(), OutIndex: uint32(i), Amount: amount, Address: key.Public().Address().Bytes()},
+			key:  key,
+		}
+	}
+	return coins
+}
+
+func TestWalletTransfer(t *testing.T) {
+	var (
+		first  = crypto.GeneratePrivateKey()
+		second = crypto.GeneratePrivateKey()
+		to     = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		query  = &fakeQuery{utxos: map[string][]*proto.UTXO{}}
+		w      = New(query, first, second)
+		ctx    = context.Background()
+	)
+	legacy := testCoins(first, 30)
+	legacy[0].UTXO.Address = first.Public().LegacyAddress().Bytes() // still spent by the key
+	for _, coin := range append(legacy, testCoins(second, 50, 100)...) {
+		addr := string(coin.UTXO.Address)
+		query.utxos[addr] = append(query.utxos[addr], coin.UTXO)
+	}
+
+	balance, err := w.Balance(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(180), balance)
+
+	// spends coins of both keys, each input signed by its own key
+	tx, err := w.Transfer(ctx, to, 140, 0, LargestFirst)
+	require.NoError(t, err)
+	require.Len(t, tx.Inputs, 2)
+	assert.Equal(t, second.Public().Bytes(), tx.Inputs[0].PublicKey)
+	assert.Equal(t, second.Public().Bytes(), tx.Inputs[1].PublicKey)
+	require.Len(t, tx.Outputs, 2)
+	assert.Equal(t, int64(10), tx.Outputs[1].Amount)
+	assert.Equal(t, first.Public().Address().Bytes(), tx.Outputs[1].Address) // change to the first key
+	assert.True(t, types.VerifyTransaction(tx))
+
+	tx, err = w.Transfer(ctx, to, 180, 0, LargestFirst)
+	require.NoError(t, err)
+	assert.Len(t, tx.Inputs, 3)
+	assert.Len(t, tx.Outputs, 1)
+	assert.True(t, types.VerifyTransaction(tx))
+
+	_, err = w.Transfer(ctx, to, 181, 0, LargestFirst)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	_, err = w.Transfer(ctx, to, 0, 0, LargestFirst)
+	assert.Error(t, err)
+}
+
+func TestBuildTransactionFee(t *testing.T) {
+	var (
+		key     = crypto.GeneratePrivateKey()
+		change  = key.Public().Address()
+		coins   = testCoins(key, 5000, 20000, 100000)
+		outputs = []*proto.TxOutput{{Amount: 30000, Address: crypto.GeneratePrivateKey().Public().Address().Bytes()}}
+		feeRate = int64(2)
+	)
+	tx, selection, err := BuildTransaction(coins, outputs, change, feeRate, LargestFirst)
+	require.NoError(t, err)
+	require.Len(t, tx.Inputs, 1)
+	require.Len(t, tx.Outputs, 2)
+
+	// the fee covers the size of the signed transaction and the outputs and fee add up to the inputs
+	assert.GreaterOrEqual(t, selection.Fee, int64(pb.Size(tx))*feeRate)
+	assert.Equal(t, int64(100000), tx.Outputs[0].Amount+tx.Outputs[1].Amount+selection.Fee)
+	assert.True(t, types.VerifyTransaction(tx))
+}